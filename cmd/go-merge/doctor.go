@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// ErrDoctorFailed is returned when any of the doctor's checks fail.
+var ErrDoctorFailed = errors.New("merge driver setup has problems")
+
+// binaryDiagnosis is the name of the check of the merge driver binary.
+const binaryDiagnosis = "merge driver binary matches this version"
+
+// diagnosis is the result of one of the doctor's checks.
+type diagnosis struct {
+	// name describes what was checked.
+	name string
+
+	// passed reports whether the check passed.
+	passed bool
+
+	// details are further lines describing the result.
+	details []string
+
+	// fix suggests how to fix a failed check.
+	fix string
+}
+
+// newDoctorCmd returns the command diagnosing the merge driver setup.
+func newDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check git is set up to use the merge driver",
+		Long: "Checks the merge driver is defined in the git config, that the gitattributes " +
+			"select it for every go.mod, go.sum, go.work, go.work.sum and vendor/modules.txt file " +
+			"in the repository, and that the driver binary exists and matches this version.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(cmd)
+		},
+	}
+}
+
+// runDoctor runs the doctor's checks, and reports the results.
+func runDoctor(cmd *cobra.Command) error {
+	ctx := cmd.Context()
+
+	topLevel, err := git.Repo{}.TopLevel(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotInRepo, err)
+	}
+
+	repo := git.Repo{Dir: topLevel}
+
+	driver, configured, err := repo.Config(ctx, "", driverSection+".driver")
+	if err != nil {
+		return fmt.Errorf("failed to read merge driver config: %w", err)
+	}
+
+	diagnoses := []diagnosis{diagnoseConfig(driver, configured)}
+
+	attributes, err := diagnoseAttributes(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	diagnoses = append(diagnoses, attributes)
+
+	if configured {
+		diagnoses = append(diagnoses, diagnoseBinary(driver))
+	}
+
+	writeDiagnoses(cmd.OutOrStdout(), diagnoses)
+
+	for _, diagnosis := range diagnoses {
+		if !diagnosis.passed {
+			return ErrDoctorFailed
+		}
+	}
+
+	return nil
+}
+
+// diagnoseConfig checks the merge driver is defined in the git config.
+func diagnoseConfig(driver string, configured bool) diagnosis {
+	if !configured {
+		return diagnosis{
+			name: "merge driver defined in git config",
+			fix:  "run `go-merge install` to define it",
+		}
+	}
+
+	return diagnosis{
+		name:    "merge driver defined in git config",
+		passed:  true,
+		details: []string{driverSection + ".driver = " + driver},
+	}
+}
+
+// diagnoseAttributes checks the gitattributes select the merge driver for every
+// file it merges, resolving them as git does.
+func diagnoseAttributes(ctx context.Context, repo git.Repo) (diagnosis, error) {
+	files, err := repo.Files(ctx)
+	if err != nil {
+		return diagnosis{}, fmt.Errorf("failed to list repository files: %w", err)
+	}
+
+	var merged []string
+
+	for _, file := range files {
+		if _, ok := attributePattern(file); ok {
+			merged = append(merged, file)
+		}
+	}
+
+	slices.Sort(merged)
+
+	values, err := repo.CheckAttr(ctx, "merge", merged)
+	if err != nil {
+		return diagnosis{}, fmt.Errorf("failed to check gitattributes: %w", err)
+	}
+
+	result := diagnosis{
+		name: "gitattributes select the merge driver",
+	}
+
+	var missing []string
+
+	for _, file := range merged {
+		if value := values[file]; value != "go" {
+			result.details = append(result.details, fmt.Sprintf("%s: merge is %s", file, value))
+
+			if pattern, _ := attributePattern(file); !slices.Contains(missing, pattern) {
+				missing = append(missing, pattern)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		lines := make([]string, 0, len(missing))
+
+		for _, pattern := range missing {
+			lines = append(lines, fmt.Sprintf("`%s %s`", pattern, driverAttribute))
+		}
+
+		result.fix = fmt.Sprintf(
+			"run `go-merge install`, or add %s to .gitattributes, after any lines setting merge for those files",
+			strings.Join(lines, ", "),
+		)
+
+		return result, nil
+	}
+
+	result.passed = true
+
+	if len(merged) == 0 {
+		result.details = []string{"no go.mod, go.sum, go.work, go.work.sum or vendor/modules.txt files found"}
+	} else {
+		result.details = []string{fmt.Sprintf("%d files use the merge driver", len(merged))}
+	}
+
+	return result, nil
+}
+
+// attributePattern returns the pattern of [attributePatterns] which selects the
+// file, at a path relative to the top of the work tree, as git matches the
+// patterns of the top-level .gitattributes file: patterns without a slash
+// match the file's name in any directory, and the others match its whole path.
+func attributePattern(file string) (string, bool) {
+	for _, pattern := range attributePatterns {
+		if strings.Contains(pattern, "/") && pattern == file || pattern == path.Base(file) {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// diagnoseBinary checks the merge driver command runs this version of the
+// driver: either a driver binary built from it, or go run of the driver package
+// at its version.
+func diagnoseBinary(driver string) diagnosis {
+	words := shellWords(driver)
+
+	if len(words) > 2 && words[0] == "go" && words[1] == "run" {
+		return diagnoseGoRun(words[2])
+	}
+
+	if len(words) == 0 {
+		return diagnosis{
+			name: binaryDiagnosis,
+			fix:  "run `go-merge install` to set the driver command",
+		}
+	}
+
+	binary, err := exec.LookPath(words[0])
+	if err != nil {
+		return diagnosis{
+			name:    binaryDiagnosis,
+			details: []string{err.Error()},
+			fix:     "run `go-merge install` to use this binary",
+		}
+	}
+
+	info, err := buildinfo.ReadFile(binary)
+	if err != nil || info.Path != driverPackage {
+		return diagnosis{
+			name:    binaryDiagnosis,
+			details: []string{binary + " isn't a build of " + driverPackage},
+			fix:     "run `go-merge install` to use this binary",
+		}
+	}
+
+	if info.Main.Version != buildVersion() {
+		return diagnosis{
+			name: binaryDiagnosis,
+			details: []string{
+				fmt.Sprintf("%s is version %s, but this is version %s", binary, info.Main.Version, buildVersion()),
+			},
+			fix: "run `go-merge install` to use this binary",
+		}
+	}
+
+	return diagnosis{
+		name:    binaryDiagnosis,
+		passed:  true,
+		details: []string{binary + " is version " + info.Main.Version},
+	}
+}
+
+// diagnoseGoRun checks a go run merge driver command runs this version of the
+// driver package.
+func diagnoseGoRun(pkg string) diagnosis {
+	if _, err := exec.LookPath("go"); err != nil {
+		return diagnosis{
+			name:    binaryDiagnosis,
+			details: []string{err.Error()},
+			fix:     "install go, or run `go-merge install --binary PATH` to use a driver binary",
+		}
+	}
+
+	pkg, version, ok := strings.Cut(pkg, "@")
+
+	switch {
+	case strings.TrimSuffix(pkg, "/") != driverPackage:
+		return diagnosis{
+			name:    binaryDiagnosis,
+			details: []string{"go run runs " + pkg + ", not " + driverPackage},
+			fix:     "run `go-merge install --go-run` to run the driver package",
+		}
+	case !ok:
+		return diagnosis{
+			name:    binaryDiagnosis,
+			passed:  true,
+			details: []string{"go run runs the version required by the repository's go.mod"},
+		}
+	case version != "latest" && version != buildVersion():
+		return diagnosis{
+			name:    binaryDiagnosis,
+			details: []string{fmt.Sprintf("go run runs version %s, but this is version %s", version, buildVersion())},
+			fix:     "run `go-merge install --go-run` to run this version",
+		}
+	default:
+		return diagnosis{
+			name:    binaryDiagnosis,
+			passed:  true,
+			details: []string{"go run runs version " + version},
+		}
+	}
+}
+
+// writeDiagnoses writes a pass/fail report of the diagnoses.
+func writeDiagnoses(w io.Writer, diagnoses []diagnosis) {
+	for _, diagnosis := range diagnoses {
+		status := "PASS"
+
+		if !diagnosis.passed {
+			status = "FAIL"
+		}
+
+		fmt.Fprintf(w, "%s %s\n", status, diagnosis.name)
+
+		for _, detail := range diagnosis.details {
+			fmt.Fprintf(w, "     %s\n", detail)
+		}
+
+		if diagnosis.fix != "" {
+			fmt.Fprintf(w, "     fix: %s\n", diagnosis.fix)
+		}
+	}
+}
+
+// shellWords splits the command into words as the shell would, handling
+// quoting but not expansions.
+func shellWords(command string) []string {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+
+			escaped = false
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == '\'':
+			word.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShellWords(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		command  string
+		expected []string
+	}{
+		{
+			name:     "words",
+			command:  "go-merge -O %O\t-A %A\n",
+			expected: []string{"go-merge", "-O", "%O", "-A", "%A"},
+		},
+		{
+			name:     "single quotes",
+			command:  `'/opt/go merge/go-merge' -O %O`,
+			expected: []string{"/opt/go merge/go-merge", "-O", "%O"},
+		},
+		{
+			name:     "quoted quote",
+			command:  `'it'\''s' "say \"hi\""`,
+			expected: []string{"it's", `say "hi"`},
+		},
+		{
+			name:     "escaped space",
+			command:  `/opt/go\ merge/go-merge`,
+			expected: []string{"/opt/go merge/go-merge"},
+		},
+		{
+			name:     "empty quotes",
+			command:  `go-merge ''`,
+			expected: []string{"go-merge", ""},
+		},
+		{
+			name:    "empty",
+			command: "  ",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, shellWords(tc.command))
+		})
+	}
+}
+
+func TestDiagnoseGoRun(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		pkg    string
+		passed bool
+	}{
+		{name: "latest", pkg: driverPackage + "@latest", passed: true},
+		{name: "this version", pkg: driverPackage + "@" + buildVersion(), passed: true},
+		{name: "go.mod version", pkg: driverPackage + "/", passed: true},
+		{name: "other version", pkg: driverPackage + "@v0.0.1", passed: false},
+		{name: "other package", pkg: "example.com/other@latest", passed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := diagnoseGoRun(tc.pkg)
+
+			assert.Equal(t, binaryDiagnosis, result.name)
+			assert.Equal(t, tc.passed, result.passed, result.details)
+
+			if !tc.passed {
+				assert.NotEmpty(t, result.fix)
+			}
+		})
+	}
+}
+
+func TestDiagnoseBinary(t *testing.T) {
+	t.Parallel()
+
+	script := filepath.Join(t.TempDir(), "go-merge")
+
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\n"), 0o755))
+
+	testCases := []struct {
+		name   string
+		driver string
+		passed bool
+	}{
+		{name: "go run", driver: "go run " + driverPackage + "@latest " + driverArgs, passed: true},
+		{name: "empty", driver: "", passed: false},
+		{name: "missing binary", driver: shellQuote(filepath.Join(t.TempDir(), "missing")) + " " + driverArgs, passed: false},
+		{name: "not a driver build", driver: shellQuote(script) + " " + driverArgs, passed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := diagnoseBinary(tc.driver)
+
+			assert.Equal(t, binaryDiagnosis, result.name)
+			assert.Equal(t, tc.passed, result.passed, result.details)
+
+			if !tc.passed {
+				assert.NotEmpty(t, result.fix)
+			}
+		})
+	}
+}
+
+func TestDiagnoseAttributes(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"go.mod":                 "module example.com/m\n",
+		"sub/go.sum":             "",
+		"vendor/modules.txt":     "",
+		"sub/vendor/modules.txt": "",
+		"README.md":              "",
+	}
+
+	testCases := []struct {
+		name       string
+		attributes string
+		files      map[string]string
+		passed     bool
+		details    []string
+		fix        string
+	}{
+		{
+			name:       "every file",
+			attributes: "go.mod merge=go\ngo.sum merge=go\nvendor/modules.txt merge=go\n",
+			files:      files,
+			passed:     true,
+			details:    []string{"3 files use the merge driver"},
+		},
+		{
+			name:       "missing vendor",
+			attributes: "go.mod merge=go\ngo.sum merge=go\n",
+			files:      files,
+			details:    []string{"vendor/modules.txt: merge is unspecified"},
+			fix:        "run `go-merge install`, or add `vendor/modules.txt merge=go` to .gitattributes, after any lines setting merge for those files",
+		},
+		{
+			name:       "overridden",
+			attributes: "*.mod merge=go\ngo.sum merge=go\nvendor/modules.txt merge=go\nsub/go.sum merge=union\n",
+			files:      files,
+			details:    []string{"sub/go.sum: merge is union"},
+			fix:        "run `go-merge install`, or add `go.sum merge=go` to .gitattributes, after any lines setting merge for those files",
+		},
+		{
+			name:    "no files",
+			files:   map[string]string{"README.md": ""},
+			passed:  true,
+			details: []string{"no go.mod, go.sum, go.work, go.work.sum or vendor/modules.txt files found"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := initRepo(t)
+			files := maps.Clone(tc.files)
+
+			if tc.attributes != "" {
+				files[".gitattributes"] = tc.attributes
+			}
+
+			for file, contents := range files {
+				target := filepath.Join(repo.Dir, filepath.FromSlash(file))
+
+				require.NoError(t, os.MkdirAll(filepath.Dir(target), 0o755))
+				require.NoError(t, os.WriteFile(target, []byte(contents), 0o644))
+			}
+
+			result, err := diagnoseAttributes(context.Background(), repo)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.passed, result.passed)
+			assert.Equal(t, tc.details, result.details)
+			assert.Equal(t, tc.fix, result.fix)
+		})
+	}
+}
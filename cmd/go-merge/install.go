@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/crystalix007/go-merge-drivers/internal/flags"
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	// driverSection is the git config section defining the merge driver.
+	driverSection = "merge.go"
+
+	// driverName is the human-readable name of the merge driver.
+	driverName = "Go merge driver"
+
+	// driverAttribute is the gitattribute selecting the merge driver.
+	driverAttribute = "merge=go"
+
+	// driverArgs are the arguments git passes the merge driver.
+	driverArgs = "-O %O -A %A -B %B -P %P -L %X -L %S -L %Y --output %A"
+
+	// driverPackage is the package run by go run.
+	driverPackage = "github.com/crystalix007/go-merge-drivers/cmd/go-merge"
+
+	// attributesMarker marks the gitattributes lines install adds, so
+	// uninstall only removes those.
+	attributesMarker = "Added by go-merge install"
+)
+
+// attributePatterns are the gitattributes patterns of the files merged with
+// the merge driver.
+var attributePatterns = []string{"go.mod", "go.sum", "go.work", "go.work.sum", "vendor/modules.txt"}
+
+// ErrNotInRepo is returned when installing the driver into the repository's
+// git config outside a git repository.
+var ErrNotInRepo = errors.New("not in a git repository")
+
+// newInstallCmd returns the command installing the merge driver.
+func newInstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Configure git to use the merge driver",
+		Long: "Defines the merge driver in the repository, global or system git config, " +
+			"and adds gitattributes using it to the repository's .gitattributes file.",
+		Args: cobra.NoArgs,
+	}
+
+	installFlags := flags.AddInstallFlags(cmd)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return runInstall(cmd, installFlags)
+	}
+
+	return cmd
+}
+
+// newUninstallCmd returns the command uninstalling the merge driver.
+func newUninstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the merge driver from the git config",
+		Long: "Removes the merge driver from the repository, global or system git config. " +
+			"Uninstalling from the repository git config also removes the gitattributes install " +
+			"added from the repository's .gitattributes file, leaving lines added any other way.",
+		Args: cobra.NoArgs,
+	}
+
+	uninstallFlags := flags.AddUninstallFlags(cmd)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return runUninstall(cmd, uninstallFlags)
+	}
+
+	return cmd
+}
+
+// runInstall defines the merge driver in the git config, and adds the
+// gitattributes using it. Installing again updates the driver command, and
+// leaves the gitattributes untouched.
+func runInstall(cmd *cobra.Command, installFlags flags.InstallFlags) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+
+	scope, err := git.ParseScope(*installFlags.Scope)
+	if err != nil {
+		return err
+	}
+
+	driver, err := driverCommand(installFlags)
+	if err != nil {
+		return err
+	}
+
+	repo := git.Repo{}
+
+	if err := repo.SetConfig(ctx, scope, driverSection+".name", driverName); err != nil {
+		return fmt.Errorf("failed to install merge driver: %w", err)
+	}
+
+	if err := repo.SetConfig(ctx, scope, driverSection+".driver", driver); err != nil {
+		return fmt.Errorf("failed to install merge driver: %w", err)
+	}
+
+	fmt.Fprintf(out, "Installed merge driver into %s git config: %s\n", scope, driver)
+
+	attributesFile, err := repoAttributesFile(cmd, scope)
+	if err != nil || attributesFile == "" {
+		return err
+	}
+
+	added, err := git.AddAttributes(attributesFile, attributePatterns, driverAttribute, attributesMarker)
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range added {
+		fmt.Fprintf(out, "Added to %s: %s %s\n", attributesFile, pattern, driverAttribute)
+	}
+
+	return nil
+}
+
+// runUninstall removes the merge driver from the git config, and the
+// gitattributes install added when uninstalling from the repository's git
+// config. The global and system git configs serve every repository, so the
+// repository's gitattributes are left alone for them.
+func runUninstall(cmd *cobra.Command, uninstallFlags flags.UninstallFlags) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+
+	scope, err := git.ParseScope(*uninstallFlags.Scope)
+	if err != nil {
+		return err
+	}
+
+	removed, err := git.Repo{}.RemoveConfigSection(ctx, scope, driverSection)
+	if err != nil {
+		return fmt.Errorf("failed to uninstall merge driver: %w", err)
+	}
+
+	if removed {
+		fmt.Fprintf(out, "Removed merge driver from %s git config\n", scope)
+	} else {
+		fmt.Fprintf(out, "Merge driver not in %s git config\n", scope)
+	}
+
+	if scope != git.ScopeRepo {
+		return nil
+	}
+
+	attributesFile, err := repoAttributesFile(cmd, scope)
+	if err != nil {
+		return err
+	}
+
+	removedPatterns, err := git.RemoveAttributes(attributesFile, attributePatterns, driverAttribute, attributesMarker)
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range removedPatterns {
+		fmt.Fprintf(out, "Removed from %s: %s %s\n", attributesFile, pattern, driverAttribute)
+	}
+
+	return nil
+}
+
+// repoAttributesFile returns the .gitattributes file at the top of the work
+// tree. Outside a work tree, there is no file to edit for the global and system
+// scopes, so an empty path is returned.
+func repoAttributesFile(cmd *cobra.Command, scope git.Scope) (string, error) {
+	topLevel, err := git.Repo{}.TopLevel(cmd.Context())
+	if err == nil {
+		return filepath.Join(topLevel, ".gitattributes"), nil
+	}
+
+	if scope == git.ScopeRepo {
+		return "", fmt.Errorf("%w: %w", ErrNotInRepo, err)
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Not in a git work tree, so not editing .gitattributes")
+
+	return "", nil
+}
+
+// driverCommand returns the command git runs for the merge driver: the driver
+// binary, or go run of the driver package at this binary's version.
+//
+// Without a binary, this binary is used, unless it is a temporary build from
+// go run, which won't outlive this run.
+func driverCommand(installFlags flags.InstallFlags) (string, error) {
+	binary := *installFlags.Binary
+
+	if binary == "" && !*installFlags.GoRun {
+		executable, err := os.Executable()
+		if err != nil {
+			return "", fmt.Errorf("failed to find driver binary: %w", err)
+		}
+
+		if !strings.Contains(executable, string(filepath.Separator)+"go-build") {
+			binary = executable
+		}
+	}
+
+	if binary == "" {
+		return fmt.Sprintf("go run %s@%s %s", driverPackage, driverVersion(), driverArgs), nil
+	}
+
+	binary, err := filepath.Abs(binary)
+	if err != nil {
+		return "", fmt.Errorf("failed to find driver binary (%s): %w", binary, err)
+	}
+
+	if _, err := os.Stat(binary); err != nil {
+		return "", fmt.Errorf("failed to find driver binary: %w", err)
+	}
+
+	return shellQuote(binary) + " " + driverArgs, nil
+}
+
+// driverVersion returns the module version this binary was built from, or
+// latest for development builds, including builds of modified sources, whose
+// versions go run can't fetch.
+func driverVersion() string {
+	version := buildVersion()
+	if !semver.IsValid(version) || semver.Build(version) != "" {
+		return "latest"
+	}
+
+	return version
+}
+
+// buildVersion returns the module version this binary was built from.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "(devel)"
+	}
+
+	return info.Main.Version
+}
+
+// shellQuote quotes the word for the shell git runs merge drivers with, if it
+// needs quoting.
+func shellQuote(word string) string {
+	safe := strings.Trim(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/._-+@:,=") == ""
+	if safe && word != "" {
+		return word
+	}
+
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriverCommand(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "bin dir")
+	binary := filepath.Join(dir, "go-merge")
+
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(binary, nil, 0o755))
+
+	goRun := "go run " + driverPackage + "@latest " + driverArgs
+
+	testCases := []struct {
+		name     string
+		binary   string
+		goRun    bool
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "binary",
+			binary:   binary,
+			expected: "'" + binary + "' " + driverArgs,
+		},
+		{
+			name:    "missing binary",
+			binary:  filepath.Join(dir, "missing"),
+			wantErr: true,
+		},
+		{
+			name:     "go run",
+			goRun:    true,
+			expected: goRun,
+		},
+		{
+			// The test binary is a temporary build, which won't outlive
+			// the test.
+			name:     "temporary build",
+			expected: goRun,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			command, err := driverCommand(flags.InstallFlags{
+				Binary: &tc.binary,
+				GoRun:  &tc.goRun,
+			})
+			if tc.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, command)
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		word     string
+		expected string
+	}{
+		{word: "/usr/local/bin/go-merge", expected: "/usr/local/bin/go-merge"},
+		{word: "go-merge@v1.2.3+build", expected: "go-merge@v1.2.3+build"},
+		{word: "", expected: "''"},
+		{word: "/opt/go merge/go-merge", expected: "'/opt/go merge/go-merge'"},
+		{word: "$HOME/go-merge", expected: "'$HOME/go-merge'"},
+		{word: "it's", expected: `'it'\''s'`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.word, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, shellQuote(tc.word))
+		})
+	}
+}
@@ -2,18 +2,38 @@ package main
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/crystalix007/go-merge-drivers/internal/config"
 	"github.com/crystalix007/go-merge-drivers/internal/flags"
+	"github.com/crystalix007/go-merge-drivers/internal/git"
 	"github.com/crystalix007/go-merge-drivers/internal/gomod"
 	"github.com/crystalix007/go-merge-drivers/internal/gosum"
+	"github.com/crystalix007/go-merge-drivers/internal/gowork"
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+	"github.com/crystalix007/go-merge-drivers/internal/sumdb"
+	"github.com/crystalix007/go-merge-drivers/internal/vendor"
 	"github.com/spf13/cobra"
+	"golang.org/x/mod/modfile"
+)
+
+// The sources the merged go.mod file can be read from, to keep the go.sum file
+// consistent with it.
+const (
+	goModWorktree = "worktree"
+	goModIndex    = "index"
 )
 
 var (
@@ -32,11 +52,32 @@ var (
 
 	// ErrNoResult is returned when the result is not provided.
 	ErrNoResult = errors.New("result is not provided")
+
+	// ErrInvalidGoModSource is returned when the go.mod source is not one of
+	// the supported sources.
+	ErrInvalidGoModSource = errors.New("go.mod source must be worktree or index")
+
+	// ErrFillNeedsGoMod is returned when filling go.sum hashes from the module
+	// cache without reading the merged go.mod.
+	ErrFillNeedsGoMod = errors.New("filling go.sum from the module cache needs --go-mod")
+
+	// ErrConflict is returned when the merge produced conflicts which need to
+	// be resolved manually.
+	ErrConflict = errors.New("merge has conflicts")
+
+	// ErrSecurity is returned when merged go.sum hashes don't match the
+	// checksum database. The hashes are written as conflicts, so it is also
+	// an [ErrConflict].
+	ErrSecurity = fmt.Errorf("SECURITY ERROR: %w", ErrConflict)
 )
 
 func main() {
 	cmd := &cobra.Command{
-		Use: "go-mod-merge",
+		Use:     "go-mod-merge",
+		Version: buildVersion(),
+		// Errors are reported below, and conflicts aren't usage errors.
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
 
 	flags := flags.AddFlags(cmd)
@@ -45,6 +86,8 @@ func main() {
 		return run(cmd, flags)
 	}
 
+	cmd.AddCommand(newInstallCmd(), newUninstallCmd(), newDoctorCmd(), newMergeCmd(), newResolveCmd())
+
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 
@@ -69,13 +112,19 @@ func run(cmd *cobra.Command, flags flags.Flags) (err error) {
 		// Defer creating the file until we've finished processing, to avoid
 		// overwriting files necessary to run the command.
 		defer func() {
+			// Leave the output untouched if the merge failed outright, but
+			// still write conflicting merges so they can be resolved.
+			if err != nil && !errors.Is(err, ErrConflict) {
+				return
+			}
+
 			var (
 				outputErr  error
 				resultFile *os.File
 			)
 
 			resultFile, outputErr = os.Create(*flags.Output)
-			if err != nil {
+			if outputErr != nil {
 				err = errors.Join(err,
 					fmt.Errorf(
 						"failed to create output file (%s): %w",
@@ -91,30 +140,117 @@ func run(cmd *cobra.Command, flags flags.Flags) (err error) {
 		}()
 	}
 
-	_, filename := path.Split(*flags.Result)
+	slog.InfoContext(
+		cmd.Context(),
+		"running merge driver",
+		slog.String("common-ancestor", *flags.CommonAncestor),
+		slog.String("current-version", *flags.CurrentVersion),
+		slog.String("other-version", *flags.OtherVersion),
+		slog.String("result", *flags.Result),
+	)
+
+	files, err := readVersions(flags)
+	if err != nil {
+		return err
+	}
+
+	opts, err := driverOptions(flags)
+	if err != nil {
+		return err
+	}
 
-	switch filename {
+	opts, err = configure(cmd.Context(), opts, filepath.Dir(*flags.Result))
+	if err != nil {
+		return err
+	}
+
+	// Reading the merged go.mod runs git, so is only done when syncing go.sum
+	// files with it is asked for.
+	if opts.syncGoSum {
+		goModPath := path.Join(path.Dir(*flags.Result), "go.mod")
+
+		opts.goMod = func(ctx context.Context) (*modfile.File, bool, error) {
+			return readMergedGoMod(ctx, git.Repo{}, goModPath, *flags.GoMod, opts.modOptions)
+		}
+	}
+
+	return mergeFile(cmd.Context(), *flags.Result, files, opts, output)
+}
+
+// versions holds the contents of the three versions of a file being merged.
+type versions struct {
+	ancestor []byte
+	current  []byte
+	other    []byte
+}
+
+// mergeOptions configures how files are merged.
+type mergeOptions struct {
+	// labels name each side in conflict markers.
+	labels markers.Labels
+
+	// modOptions configures how go.mod and go.work files are merged.
+	modOptions gomod.Options
+
+	// syncGoSum keeps merged go.sum files consistent with the merged go.mod
+	// file goMod returns.
+	syncGoSum bool
+
+	// goMod returns the merged go.mod file next to the file being merged, and
+	// whether it was merged from the go.mod files of the merge's sides, rather
+	// than read from a file which may not hold the other side's changes yet.
+	// Merged go.sum files aren't checked against the go.mod file if it is nil.
+	goMod func(ctx context.Context) (*modfile.File, bool, error)
+
+	// workspaceSums returns the go.sum files of the modules in the workspace,
+	// whose hashes are pruned from a merged go.work.sum file. Nothing is
+	// pruned if it is nil.
+	workspaceSums func(ctx context.Context) []gosum.GoSum
+
+	// fillFromCache fills missing go.sum hashes from the module cache, when
+	// syncGoSum is set.
+	fillFromCache bool
+
+	// verify verifies new go.sum hashes against the checksum database.
+	verify bool
+
+	// gosumdb is the checksum database to verify against, in the form of
+	// GOSUMDB. $GOSUMDB is used if it is empty.
+	gosumdb string
+}
+
+// mergeFile merges the versions of the go module file at the path, relative
+// to the top of the work tree, writing the merged file to output. The merged
+// file is written even when the merge has conflicts, with conflict markers
+// around each conflict, and [ErrConflict] returned.
+func mergeFile(ctx context.Context, file string, files versions, opts mergeOptions, output io.Writer) error {
+	switch filename := path.Base(file); filename {
 	case "go.mod":
-		return runGoModMerge(cmd.Context(), flags, output)
+		return runGoModMerge(ctx, file, files, opts, output)
 	case "go.sum":
-		return runGoSumMerge(cmd.Context(), flags, output)
+		return runGoSumMerge(ctx, file, files, opts, output, nil)
+	case "go.work.sum":
+		var members []gosum.GoSum
+
+		if opts.workspaceSums != nil {
+			members = opts.workspaceSums(ctx)
+		}
+
+		return runGoSumMerge(ctx, file, files, opts, output, members)
+	case "modules.txt":
+		return runVendorMerge(ctx, file, files, opts, output)
+	case "go.work":
+		return runGoWorkMerge(ctx, file, files, opts, output)
 	default:
 		return fmt.Errorf("%w: %s", ErrUnknownFile, filename)
 	}
 }
 
 // runGoModMerge will run the go.mod merge operation.
-func runGoModMerge(ctx context.Context, flags flags.Flags, output io.Writer) error {
-	slog.InfoContext(
-		ctx,
-		"running go.mod merge",
-		slog.String("common-ancestor", *flags.CommonAncestor),
-		slog.String("current-version", *flags.CurrentVersion),
-		slog.String("other-version", *flags.OtherVersion),
-		slog.String("result", *flags.Result),
-	)
+func runGoModMerge(ctx context.Context, file string, files versions, opts mergeOptions, output io.Writer) error {
+	slog.InfoContext(ctx, "running go.mod merge", slog.String("file", file))
 
-	commonAncestor, err := gomod.Parse(*flags.CommonAncestor)
+	commonAncestor, err := gomod.ParseData(file, files.ancestor)
 	if err != nil {
 		return fmt.Errorf(
 			"failed to parse common ancestor: %w",
@@ -122,7 +258,7 @@ func runGoModMerge(ctx context.Context, flags flags.Flags, output io.Writer) err
 		)
 	}
 
-	currentVersion, err := gomod.Parse(*flags.CurrentVersion)
+	currentVersion, err := gomod.ParseData(file, files.current)
 	if err != nil {
 		return fmt.Errorf(
 			"failed to parse current version: %w",
@@ -130,7 +266,7 @@ func runGoModMerge(ctx context.Context, flags flags.Flags, output io.Writer) err
 		)
 	}
 
-	otherVersion, err := gomod.Parse(*flags.OtherVersion)
+	otherVersion, err := gomod.ParseData(file, files.other)
 	if err != nil {
 		return fmt.Errorf(
 			"failed to parse other version: %w",
@@ -139,9 +275,15 @@ func runGoModMerge(ctx context.Context, flags flags.Flags, output io.Writer) err
 	}
 
 	// Merge the go.mod file changes.
-	merged := gomod.Merge(*currentVersion, *otherVersion, *commonAncestor)
+	merged, conflicts, err := gomod.MergeWithOptions(*currentVersion, *otherVersion, *commonAncestor, opts.modOptions)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to merge go.mod file: %w",
+			err,
+		)
+	}
 
-	mergedBytes, err := merged.Format()
+	mergedBytes, err := gomod.FormatConflicts(merged, conflicts, opts.labels)
 	if err != nil {
 		return fmt.Errorf(
 			"failed to format go.mod file: %w",
@@ -152,26 +294,166 @@ func runGoModMerge(ctx context.Context, flags flags.Flags, output io.Writer) err
 	if _, err := output.Write(mergedBytes); err != nil {
 		return fmt.Errorf(
 			"failed to write go.mod file (%s): %w",
-			*flags.Result,
+			file,
 			err,
 		)
 	}
 
-	return nil
+	for _, conflict := range conflicts {
+		slog.WarnContext(
+			ctx,
+			"go.mod merge conflict",
+			slog.String("file", file),
+			slog.String("conflict", conflict.String()),
+		)
+	}
+
+	return conflictError(file, conflicts)
 }
 
-// runGoSumMerge will run the go.sum merge operation.
-func runGoSumMerge(ctx context.Context, flags flags.Flags, output io.Writer) error {
-	slog.InfoContext(
-		ctx,
-		"running go.sum merge",
-		slog.String("common-ancestor", *flags.CommonAncestor),
-		slog.String("current-version", *flags.CurrentVersion),
-		slog.String("other-version", *flags.OtherVersion),
-		slog.String("result", *flags.Result),
+// runGoWorkMerge will run the go.work merge operation.
+func runGoWorkMerge(ctx context.Context, file string, files versions, opts mergeOptions, output io.Writer) error {
+	slog.InfoContext(ctx, "running go.work merge", slog.String("file", file))
+
+	commonAncestor, err := gowork.ParseData(file, files.ancestor)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to parse common ancestor: %w",
+			err,
+		)
+	}
+
+	currentVersion, err := gowork.ParseData(file, files.current)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to parse current version: %w",
+			err,
+		)
+	}
+
+	otherVersion, err := gowork.ParseData(file, files.other)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to parse other version: %w",
+			err,
+		)
+	}
+
+	// Merge the go.work file changes.
+	merged, conflicts, err := gowork.MergeWithOptions(*currentVersion, *otherVersion, *commonAncestor, opts.modOptions)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to merge go.work file: %w",
+			err,
+		)
+	}
+
+	mergedBytes, err := gowork.FormatConflicts(merged, conflicts, opts.labels)
+	if err != nil {
+		return err
+	}
+
+	if _, err := output.Write(mergedBytes); err != nil {
+		return fmt.Errorf(
+			"failed to write go.work file (%s): %w",
+			file,
+			err,
+		)
+	}
+
+	for _, conflict := range conflicts {
+		slog.WarnContext(
+			ctx,
+			"go.work merge conflict",
+			slog.String("file", file),
+			slog.String("conflict", conflict.String()),
+		)
+	}
+
+	return conflictError(file, conflicts)
+}
+
+// conflictError returns the error reporting the conflicts of the merged go.mod
+// or go.work file, naming the modules of those reported by a merge policy, and
+// the policy, or nil if there are no conflicts.
+func conflictError(file string, conflicts []gomod.Conflict) error {
+	var policies []string
+
+	for _, conflict := range conflicts {
+		if conflict.Policy != "" {
+			policies = append(policies, fmt.Sprintf("%s (%s policy)", conflict.Path, conflict.Policy))
+		}
+	}
+
+	return policyConflictError(file, len(conflicts), policies)
+}
+
+// vendorConflictError returns the error reporting the conflicts of the merged
+// modules.txt file, like [conflictError].
+func vendorConflictError(file string, conflicts []vendor.Conflict) error {
+	var policies []string
+
+	for _, conflict := range conflicts {
+		if conflict.Policy != "" {
+			policies = append(policies, fmt.Sprintf("%s (%s policy)", conflict.Path, conflict.Policy))
+		}
+	}
+
+	return policyConflictError(file, len(conflicts), policies)
+}
+
+// policyConflictError returns the error reporting the number of conflicts in
+// the file, listing those reported by a merge policy, or nil if there are no
+// conflicts.
+func policyConflictError(file string, conflicts int, policies []string) error {
+	if conflicts == 0 {
+		return nil
+	}
+
+	if len(policies) == 0 {
+		return fmt.Errorf("%w: %d in %s", ErrConflict, conflicts, file)
+	}
+
+	return fmt.Errorf(
+		"%w: %d in %s, including %s",
+		ErrConflict,
+		conflicts,
+		file,
+		strings.Join(slices.Compact(policies), ", "),
 	)
+}
+
+// conflictedFilesError returns the error reporting the files a merge left
+// conflicted, which is an [ErrSecurity] if any of them has hashes which don't
+// match the checksum database, or nil if there are none.
+func conflictedFilesError(conflicted []string, insecure bool) error {
+	if len(conflicted) == 0 {
+		return nil
+	}
+
+	if insecure {
+		return fmt.Errorf("%w: in %s", ErrSecurity, strings.Join(conflicted, ", "))
+	}
 
-	current, err := parseGoSumFile(*flags.CurrentVersion)
+	return fmt.Errorf("%w: in %s", ErrConflict, strings.Join(conflicted, ", "))
+}
+
+// runGoSumMerge will run the go.sum merge operation, for go.sum and
+// go.work.sum files. Hashes held by any of the member go.sum files are pruned
+// from the merged file.
+func runGoSumMerge(
+	ctx context.Context,
+	file string,
+	files versions,
+	opts mergeOptions,
+	output io.Writer,
+	members []gosum.GoSum,
+) error {
+	filename := path.Base(file)
+
+	slog.InfoContext(ctx, "running go.sum merge", slog.String("file", file))
+
+	current, err := gosum.NewGoSum(bytes.NewReader(files.current))
 	if err != nil {
 		return fmt.Errorf(
 			"failed to parse current go.sum file: %w",
@@ -179,7 +461,7 @@ func runGoSumMerge(ctx context.Context, flags flags.Flags, output io.Writer) err
 		)
 	}
 
-	other, err := parseGoSumFile(*flags.OtherVersion)
+	other, err := gosum.NewGoSum(bytes.NewReader(files.other))
 	if err != nil {
 		return fmt.Errorf(
 			"failed to parse other go.sum file: %w",
@@ -187,7 +469,7 @@ func runGoSumMerge(ctx context.Context, flags flags.Flags, output io.Writer) err
 		)
 	}
 
-	ancestor, err := parseGoSumFile(*flags.CommonAncestor)
+	ancestor, err := gosum.NewGoSum(bytes.NewReader(files.ancestor))
 	if err != nil {
 		return fmt.Errorf(
 			"failed to parse common ancestor go.sum file: %w",
@@ -195,25 +477,589 @@ func runGoSumMerge(ctx context.Context, flags flags.Flags, output io.Writer) err
 		)
 	}
 
-	merged, err := gosum.Merge(current, other, ancestor)
+	merged, conflicts := gosum.MergeConflicts(current, other, ancestor)
+
+	merged = gosum.Prune(merged, members...)
+
+	merged, denied := gosum.PruneVersions(merged, current, opts.modOptions.Denied)
+
+	for _, key := range denied {
+		slog.WarnContext(
+			ctx,
+			"dropped go.sum hash of denied module version",
+			slog.String("file", file),
+			slog.String("module", key.String()),
+		)
+	}
+
+	if filename == "go.sum" && opts.syncGoSum && opts.goMod != nil {
+		merged = syncGoSum(ctx, opts, merged, current, other, ancestor)
+	}
+
+	if filename == "go.sum" && opts.goMod != nil {
+		warnMigratedHashes(ctx, opts, merged)
+	}
+
+	if opts.verify {
+		securityConflicts, err := verifyGoSum(ctx, opts, merged, ancestor)
+		if err != nil {
+			return err
+		}
+
+		// Hashes the checksum database disagrees with can't be trusted.
+		for _, conflict := range securityConflicts {
+			delete(merged, conflict.Key)
+		}
+
+		conflicts = append(conflicts, securityConflicts...)
+	}
+
+	result := gosum.FormatConflicts(merged, conflicts, opts.labels)
+
+	if _, err := output.Write([]byte(result)); err != nil {
+		return fmt.Errorf(
+			"failed to write %s file (%s): %w",
+			filename,
+			file,
+			err,
+		)
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	var mismatched, insecure []string
+
+	for _, conflict := range conflicts {
+		slog.WarnContext(
+			ctx,
+			"go.sum merge conflict",
+			slog.String("file", file),
+			slog.String("conflict", conflict.String()),
+		)
+
+		if conflict.Security() {
+			insecure = append(insecure, conflict.Key.String())
+
+			continue
+		}
+
+		mismatched = append(mismatched, conflict.Key.String())
+	}
+
+	if len(insecure) > 0 {
+		return fmt.Errorf(
+			"%w: checksum database mismatch in %s for %s",
+			ErrSecurity,
+			file,
+			strings.Join(insecure, ", "),
+		)
+	}
+
+	return fmt.Errorf(
+		"%w: hash mismatch in %s for %s",
+		ErrConflict,
+		file,
+		strings.Join(mismatched, ", "),
+	)
+}
+
+// runVendorMerge will run the vendor/modules.txt merge operation.
+func runVendorMerge(ctx context.Context, file string, files versions, opts mergeOptions, output io.Writer) error {
+	slog.InfoContext(ctx, "running modules.txt merge", slog.String("file", file))
+
+	commonAncestor, err := vendor.Read(bytes.NewReader(files.ancestor))
 	if err != nil {
 		return fmt.Errorf(
-			"failed to merge go.sum files: %w",
+			"failed to parse common ancestor: %w",
+			err,
+		)
+	}
+
+	currentVersion, err := vendor.Read(bytes.NewReader(files.current))
+	if err != nil {
+		return fmt.Errorf(
+			"failed to parse current version: %w",
+			err,
+		)
+	}
+
+	otherVersion, err := vendor.Read(bytes.NewReader(files.other))
+	if err != nil {
+		return fmt.Errorf(
+			"failed to parse other version: %w",
 			err,
 		)
 	}
 
-	result := merged.String()
+	merged, conflicts := vendor.MergeWithOptions(*currentVersion, *otherVersion, *commonAncestor, opts.modOptions)
+
+	result := vendor.FormatConflicts(merged, conflicts, opts.labels)
 
 	if _, err := output.Write([]byte(result)); err != nil {
 		return fmt.Errorf(
-			"failed to write go.sum file (%s): %w",
-			*flags.Result,
+			"failed to write modules.txt file (%s): %w",
+			file,
 			err,
 		)
 	}
 
-	return nil
+	for _, conflict := range conflicts {
+		slog.WarnContext(
+			ctx,
+			"modules.txt merge conflict",
+			slog.String("file", file),
+			slog.String("conflict", conflict.String()),
+		)
+	}
+
+	return vendorConflictError(file, conflicts)
+}
+
+// syncGoSum keeps the merged go.sum file consistent with the merged go.mod
+// file, pruning the hashes of module versions it can no longer reach, and
+// either filling missing hashes from the module cache or warning about missing
+// go.mod hashes. The earlier go.sum files tell which modules provide packages,
+// and so need module hashes. If the go.mod file wasn't merged from the merge's
+// sides, the hashes either side added are kept, as it may not require their
+// modules yet. A go.mod file which can't be read only skips this.
+func syncGoSum(ctx context.Context, opts mergeOptions, merged, current, other, ancestor gosum.GoSum) gosum.GoSum {
+	mod, fromSides, err := opts.goMod(ctx)
+	if err != nil {
+		slog.WarnContext(
+			ctx,
+			"not checking go.sum against the merged go.mod",
+			slog.String("error", err.Error()),
+		)
+
+		return merged
+	}
+
+	pruned := gosum.PruneUnreachable(merged, *mod)
+
+	if !fromSides {
+		var kept []gosum.GoSumKey
+
+		pruned, kept = gosum.KeepAdded(pruned, merged, ancestor)
+
+		for _, key := range kept {
+			slog.DebugContext(
+				ctx,
+				"kept new go.sum hash the go.mod doesn't require yet",
+				slog.String("module", key.String()),
+			)
+		}
+	}
+
+	slog.InfoContext(
+		ctx,
+		"pruned unreachable go.sum hashes",
+		slog.Int("pruned", len(merged)-len(pruned)),
+	)
+
+	if !opts.fillFromCache {
+		for _, key := range gosum.MissingGoModHashes(pruned, *mod) {
+			slog.WarnContext(
+				ctx,
+				"go.sum is missing a go.mod hash, run go mod tidy to add it",
+				slog.String("module", key.String()),
+			)
+		}
+
+		return pruned
+	}
+
+	missing := append(
+		gosum.MissingGoModHashes(pruned, *mod),
+		gosum.MissingModuleHashes(pruned, *mod, current, other, ancestor)...,
+	)
+
+	cache := gosum.DefaultModCache()
+
+	filled, notFound := gosum.Fill(pruned, missing, cache)
+
+	slog.InfoContext(
+		ctx,
+		"filled missing go.sum hashes from the module cache",
+		slog.String("module-cache", cache.Dir),
+		slog.Int("filled", len(missing)-len(notFound)),
+	)
+
+	for _, key := range notFound {
+		slog.WarnContext(
+			ctx,
+			"go.sum hash is not in the module cache, run go mod tidy to add it",
+			slog.String("module", key.String()),
+		)
+	}
+
+	return filled
+}
+
+// warnMigratedHashes warns about the module hashes the merged go.sum file holds
+// for modules the merged go.mod file requires at another major version path
+// instead, such as those left behind when the merge collapsed a module into
+// its migration. A go.mod file which can't be read, or wasn't merged from the
+// merge's sides, skips the check.
+func warnMigratedHashes(ctx context.Context, opts mergeOptions, merged gosum.GoSum) {
+	mod, fromSides, err := opts.goMod(ctx)
+	if err != nil {
+		slog.DebugContext(
+			ctx,
+			"not checking go.sum for migrated modules",
+			slog.String("error", err.Error()),
+		)
+
+		return
+	}
+
+	if !fromSides {
+		return
+	}
+
+	for _, key := range gosum.MigratedModuleHashes(merged, *mod) {
+		slog.WarnContext(
+			ctx,
+			"go.sum has a hash of a module migrated to another major version, run go mod tidy to remove it",
+			slog.String("module", key.String()),
+		)
+	}
+}
+
+// verifyGoSum verifies the hashes of the merged go.sum file which are new or
+// changed since the common ancestor against the checksum database, returning a
+// security conflict for each hash the database disagrees with.
+func verifyGoSum(ctx context.Context, opts mergeOptions, merged, ancestor gosum.GoSum) ([]gosum.Conflict, error) {
+	gosumdb := cmp.Or(opts.gosumdb, os.Getenv("GOSUMDB"), sumdb.DefaultGOSUMDB)
+	nosumdb := cmp.Or(os.Getenv("GONOSUMDB"), os.Getenv("GOPRIVATE"))
+
+	verifier, err := sumdb.NewVerifier(ctx, gosumdb, nosumdb)
+	if errors.Is(err, sumdb.ErrDisabled) {
+		slog.WarnContext(ctx, "not verifying go.sum hashes, as GOSUMDB=off")
+
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to configure checksum database: %w",
+			err,
+		)
+	}
+
+	added, modified, _ := gosum.Diff(merged, ancestor)
+
+	changed := slices.AppendSeq(slices.Collect(maps.Keys(added)), maps.Keys(modified))
+	slices.SortFunc(changed, gosum.CompareKeys)
+
+	conflicts, unverified, err := verifier.Verify(merged, changed)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(
+		ctx,
+		"verified go.sum hashes with checksum database",
+		slog.String("sumdb", verifier.Name),
+		slog.Int("verified", len(changed)-len(unverified)),
+	)
+
+	for _, key := range unverified {
+		slog.WarnContext(
+			ctx,
+			"go.sum hash could not be verified with checksum database",
+			slog.String("sumdb", verifier.Name),
+			slog.String("module", key.String()),
+		)
+	}
+
+	return conflicts, nil
+}
+
+// readVersions reads the three versions of the file being merged, from the
+// temporary files git passes the merge driver.
+func readVersions(flags flags.Flags) (versions, error) {
+	ancestor, err := os.ReadFile(*flags.CommonAncestor)
+	if err != nil {
+		return versions{}, fmt.Errorf(
+			"failed to read common ancestor (%s): %w",
+			*flags.CommonAncestor,
+			err,
+		)
+	}
+
+	current, err := os.ReadFile(*flags.CurrentVersion)
+	if err != nil {
+		return versions{}, fmt.Errorf(
+			"failed to read current version (%s): %w",
+			*flags.CurrentVersion,
+			err,
+		)
+	}
+
+	other, err := os.ReadFile(*flags.OtherVersion)
+	if err != nil {
+		return versions{}, fmt.Errorf(
+			"failed to read other version (%s): %w",
+			*flags.OtherVersion,
+			err,
+		)
+	}
+
+	return versions{
+		ancestor: ancestor,
+		current:  current,
+		other:    other,
+	}, nil
+}
+
+// driverOptions returns the merge options configured by the merge driver's
+// flags.
+func driverOptions(flags flags.Flags) (mergeOptions, error) {
+	modOptions, err := strategyOptions(flags.Strategies)
+	if err != nil {
+		return mergeOptions{}, err
+	}
+
+	return mergeOptions{
+		labels:        labels(flags),
+		modOptions:    modOptions,
+		syncGoSum:     *flags.GoMod != "",
+		fillFromCache: *flags.FillFromCache,
+		verify:        *flags.Verify,
+		gosumdb:       *flags.GOSUMDB,
+		workspaceSums: func(ctx context.Context) []gosum.GoSum {
+			return workspaceGoSums(ctx, filepath.Dir(*flags.Result))
+		},
+	}, nil
+}
+
+// configure returns the merge options with the repository config governing the
+// directory applied. The options' strategies and pseudo-version rule, set by
+// flags, take precedence over the config's, and the config's go.sum settings can only turn pruning
+// and verification on.
+func configure(ctx context.Context, opts mergeOptions, dir string) (mergeOptions, error) {
+	opts.modOptions.Logger = slog.Default()
+
+	cfg, file, err := config.Find(dir)
+	if err != nil {
+		return mergeOptions{}, err
+	}
+
+	if file == "" {
+		return opts, nil
+	}
+
+	slog.InfoContext(ctx, "using merge config", slog.String("config", file))
+
+	modOptions, err := cfg.ModOptions()
+	if err != nil {
+		return mergeOptions{}, err
+	}
+
+	if opts.modOptions.DefaultStrategy != nil {
+		modOptions.DefaultStrategy = opts.modOptions.DefaultStrategy
+	}
+
+	if opts.modOptions.Pseudo != "" {
+		modOptions.Pseudo = opts.modOptions.Pseudo
+	}
+
+	modOptions.Strategies = append(slices.Clone(opts.modOptions.Strategies), modOptions.Strategies...)
+	modOptions.Logger = opts.modOptions.Logger
+
+	opts.modOptions = modOptions
+	opts.syncGoSum = opts.syncGoSum || cfg.GoSum.Prune
+	opts.verify = opts.verify || cfg.GoSum.Verify
+
+	return opts, nil
+}
+
+// strategyOptions returns the go.mod merge options selecting the version
+// selection strategies configured by the flags.
+func strategyOptions(strategyFlags flags.StrategyFlags) (gomod.Options, error) {
+	var opts gomod.Options
+
+	if *strategyFlags.Strategy != "" {
+		strategy, err := gomod.ParseStrategy(*strategyFlags.Strategy)
+		if err != nil {
+			return gomod.Options{}, err
+		}
+
+		opts.DefaultStrategy = strategy
+	}
+
+	if *strategyFlags.PseudoVersions != "" {
+		rule, err := gomod.ParsePseudoRule(*strategyFlags.PseudoVersions)
+		if err != nil {
+			return gomod.Options{}, err
+		}
+
+		opts.Pseudo = rule
+	}
+
+	for _, rule := range *strategyFlags.ModuleStrategies {
+		strategyRule, err := gomod.ParseStrategyRule(rule)
+		if err != nil {
+			return gomod.Options{}, err
+		}
+
+		opts.Strategies = append(opts.Strategies, strategyRule)
+	}
+
+	return opts, nil
+}
+
+// readMergedGoMod returns the merged go.mod file at the path, relative to the
+// top of the work tree. During a merge, it merges the go.mod files of the merge
+// base, HEAD and MERGE_HEAD, as the go.mod merge driver does, so it holds both
+// sides' changes whichever file git merges first. Otherwise, or if those don't
+// merge cleanly, it reads the go.mod file from the worktree or the index, as
+// the source selects, which may not hold the other side's changes yet. The
+// returned bool reports whether the file was merged from the merge's sides.
+func readMergedGoMod(
+	ctx context.Context,
+	repo git.Repo,
+	goModPath string,
+	source string,
+	modOptions gomod.Options,
+) (*modfile.File, bool, error) {
+	mod, err := mergeCommitGoMods(ctx, repo, goModPath, modOptions)
+	if err == nil {
+		return mod, true, nil
+	}
+
+	slog.DebugContext(
+		ctx,
+		"not merging go.mod from the merge's commits",
+		slog.String("error", err.Error()),
+	)
+
+	if source == goModWorktree || source == "" {
+		mod, err := gomod.Parse(filepath.Join(repo.Dir, filepath.FromSlash(goModPath)))
+
+		return mod, false, err
+	}
+
+	data, err := repo.ReadIndex(ctx, 0, goModPath)
+	if err != nil {
+		return nil, false, fmt.Errorf(
+			"failed to read go.mod from the index: %w",
+			err,
+		)
+	}
+
+	mod, err = gomod.ParseData(goModPath, data)
+
+	return mod, false, err
+}
+
+// mergeCommitGoMods merges the go.mod files at the path from the merge base,
+// HEAD and MERGE_HEAD, returning an error if there is no merge in progress, or
+// the merge has conflicts.
+func mergeCommitGoMods(ctx context.Context, repo git.Repo, goModPath string, modOptions gomod.Options) (*modfile.File, error) {
+	base, err := repo.MergeBase(ctx, "HEAD", "MERGE_HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the merge base: %w", err)
+	}
+
+	revs := []string{base, "HEAD", "MERGE_HEAD"}
+	mods := make([]modfile.File, len(revs))
+
+	for i, rev := range revs {
+		data, err := repo.ReadCommit(ctx, rev, goModPath)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to read go.mod from %s: %w",
+				rev,
+				err,
+			)
+		}
+
+		mod, err := gomod.ParseData(goModPath, data)
+		if err != nil {
+			return nil, err
+		}
+
+		mods[i] = *mod
+	}
+
+	// The go.mod merge driver logs the merge's choices already.
+	modOptions.Logger = nil
+
+	merged, conflicts, err := gomod.MergeWithOptions(mods[1], mods[2], mods[0], modOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge go.mod: %w", err)
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf(
+			"failed to merge go.mod: %d conflicts",
+			len(conflicts),
+		)
+	}
+
+	return &merged, nil
+}
+
+// workspaceGoSums reads the go.sum files of the modules used by the go.work
+// file in the directory, so hashes they already hold can be pruned from the
+// go.work.sum file. A missing or unreadable go.work file, or member go.sum file,
+// only skips pruning against those hashes.
+func workspaceGoSums(ctx context.Context, dir string) []gosum.GoSum {
+	work, err := gowork.Parse(filepath.Join(dir, "go.work"))
+	if err != nil {
+		slog.WarnContext(
+			ctx,
+			"not pruning go.work.sum against workspace modules",
+			slog.String("error", err.Error()),
+		)
+
+		return nil
+	}
+
+	var members []gosum.GoSum
+
+	for _, moduleDir := range gowork.ModuleDirs(*work, dir) {
+		member, err := parseGoSumFile(filepath.Join(moduleDir, "go.sum"))
+		if errors.Is(err, fs.ErrNotExist) {
+			// Modules without dependencies have no go.sum file.
+			continue
+		}
+
+		if err != nil {
+			slog.WarnContext(
+				ctx,
+				"not pruning go.work.sum against workspace module",
+				slog.String("module", moduleDir),
+				slog.String("error", err.Error()),
+			)
+
+			continue
+		}
+
+		members = append(members, member)
+	}
+
+	return members
+}
+
+// labels returns the conflict marker labels set by the -L flags, which git
+// passes in the order of the current, common ancestor and other versions. Sides
+// without a label use the default labels.
+func labels(flags flags.Flags) markers.Labels {
+	labels := markers.DefaultLabels
+
+	if len(*flags.Labels) > 0 {
+		labels.Current = (*flags.Labels)[0]
+	}
+
+	if len(*flags.Labels) > 2 {
+		labels.Other = (*flags.Labels)[2]
+	}
+
+	return labels
 }
 
 func parseGoSumFile(path string) (gosum.GoSum, error) {
@@ -257,5 +1103,15 @@ func check(flags flags.Flags) error {
 		return ErrNoResult
 	}
 
+	switch *flags.GoMod {
+	case "", goModWorktree, goModIndex:
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidGoModSource, *flags.GoMod)
+	}
+
+	if *flags.FillFromCache && *flags.GoMod == "" {
+		return ErrFillNeedsGoMod
+	}
+
 	return nil
 }
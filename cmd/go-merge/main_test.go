@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	ancestorGoMod = `module example.com/m
+
+go 1.22
+
+require example.com/a v1.0.0
+`
+
+	currentGoMod = `module example.com/m
+
+go 1.22
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.0.0
+)
+`
+
+	otherGoMod = `module example.com/m
+
+go 1.22
+
+require example.com/a v1.1.0
+`
+
+	ancestorGoSum = `example.com/a v1.0.0 h1:a100=
+example.com/a v1.0.0/go.mod h1:a100mod=
+`
+
+	currentGoSum = ancestorGoSum + `example.com/b v1.0.0 h1:b100=
+example.com/b v1.0.0/go.mod h1:b100mod=
+`
+
+	otherGoSum = ancestorGoSum + `example.com/a v1.1.0 h1:a110=
+example.com/a v1.1.0/go.mod h1:a110mod=
+`
+)
+
+func TestRunGoSumMerge_syncsWithCommitGoMods(t *testing.T) {
+	t.Parallel()
+
+	repo := initMergeRepo(t)
+
+	// Check the go.sum merge against the go.mod files of the merge's commits,
+	// as the worktree holds HEAD's go.mod until git merges it.
+	runGit(t, repo, "update-ref", "MERGE_HEAD", "other")
+
+	output := mergeGoSum(t, repo)
+
+	assert.Equal(
+		t,
+		`example.com/a v1.0.0/go.mod h1:a100mod=
+example.com/a v1.1.0 h1:a110=
+example.com/a v1.1.0/go.mod h1:a110mod=
+example.com/b v1.0.0 h1:b100=
+example.com/b v1.0.0/go.mod h1:b100mod=
+`,
+		output,
+	)
+}
+
+func TestRunGoSumMerge_keepsAddedWithoutMerge(t *testing.T) {
+	t.Parallel()
+
+	repo := initMergeRepo(t)
+
+	// Without a merge in progress, only the worktree's go.mod can be read,
+	// which doesn't require the other side's bump yet, so hashes either side
+	// added are kept.
+	output := mergeGoSum(t, repo)
+
+	assert.Equal(
+		t,
+		`example.com/a v1.0.0 h1:a100=
+example.com/a v1.0.0/go.mod h1:a100mod=
+example.com/a v1.1.0 h1:a110=
+example.com/a v1.1.0/go.mod h1:a110mod=
+example.com/b v1.0.0 h1:b100=
+example.com/b v1.0.0/go.mod h1:b100mod=
+`,
+		output,
+	)
+}
+
+// mergeGoSum merges the go.sum files, keeping the merged go.sum file consistent
+// with the merged go.mod file of the repository, returning the merged go.sum
+// file.
+func mergeGoSum(t *testing.T, repo git.Repo) string {
+	t.Helper()
+
+	opts := mergeOptions{
+		syncGoSum: true,
+		goMod: func(ctx context.Context) (*modfile.File, bool, error) {
+			return readMergedGoMod(ctx, repo, "go.mod", goModWorktree, gomod.Options{})
+		},
+	}
+
+	files := versions{
+		ancestor: []byte(ancestorGoSum),
+		current:  []byte(currentGoSum),
+		other:    []byte(otherGoSum),
+	}
+
+	var output bytes.Buffer
+
+	require.NoError(t, runGoSumMerge(context.Background(), "go.sum", files, opts, &output, nil))
+
+	return output.String()
+}
+
+// initMergeRepo creates a repository whose other branch bumps a requirement,
+// and whose checked out branch adds another, since their common ancestor.
+func initMergeRepo(t *testing.T) git.Repo {
+	t.Helper()
+
+	repo := initRepo(t)
+
+	commitFiles(t, repo, "base", ancestorGoMod, ancestorGoSum)
+
+	runGit(t, repo, "checkout", "--quiet", "-b", "other")
+	commitFiles(t, repo, "bump a", otherGoMod, otherGoSum)
+
+	runGit(t, repo, "checkout", "--quiet", "-")
+	commitFiles(t, repo, "add b", currentGoMod, currentGoSum)
+
+	return repo
+}
+
+// commitFiles commits the go.mod and go.sum files to the repository.
+func commitFiles(t *testing.T, repo git.Repo, message, goMod, goSum string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "go.mod"), []byte(goMod), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "go.sum"), []byte(goSum), 0o644))
+
+	runGit(t, repo, "add", "go.mod", "go.sum")
+	runGit(t, repo, "commit", "--quiet", "-m", message)
+}
+
+// initRepo creates an empty repository in a temporary directory.
+func initRepo(t *testing.T) git.Repo {
+	t.Helper()
+
+	repo := git.Repo{Dir: t.TempDir()}
+
+	runGit(t, repo, "init", "--quiet")
+	runGit(t, repo, "config", "user.name", "Test")
+	runGit(t, repo, "config", "user.email", "test@example.com")
+
+	return repo
+}
+
+// runGit runs the git command in the repository, failing the test if it fails.
+func runGit(t *testing.T, repo git.Repo, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.Dir
+
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
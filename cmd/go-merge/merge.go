@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/crystalix007/go-merge-drivers/internal/flags"
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/crystalix007/go-merge-drivers/internal/gosum"
+	"github.com/crystalix007/go-merge-drivers/internal/gowork"
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/modfile"
+)
+
+// ErrFillNeedsSync is returned when filling go.sum hashes from the module cache
+// without syncing go.sum files with the merged go.mod files.
+var ErrFillNeedsSync = errors.New("filling go.sum from the module cache needs --sync-go-sum")
+
+// newMergeCmd returns the command merging the go module files of two commits.
+func newMergeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge <current> <other>",
+		Short: "Merge the go module files of two commits",
+		Long: "Finds the merge base of the two commits, and merges every go.mod, go.sum, go.work, " +
+			"go.work.sum and vendor/modules.txt file either commit changed since it as the merge " +
+			"driver would, printing the merged files, or writing them to the work tree with --write, " +
+			"to preview the merge of dependencies before merging.",
+		Args: cobra.ExactArgs(2),
+	}
+
+	mergeFlags := flags.AddMergeFlags(cmd)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return runMerge(cmd, mergeFlags, args[0], args[1])
+	}
+
+	return cmd
+}
+
+// runMerge merges the go module files of the current and other commits.
+func runMerge(cmd *cobra.Command, mergeFlags flags.MergeFlags, current, other string) error {
+	ctx := cmd.Context()
+
+	if *mergeFlags.FillFromCache && !*mergeFlags.SyncGoSum {
+		return ErrFillNeedsSync
+	}
+
+	topLevel, err := git.Repo{}.TopLevel(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotInRepo, err)
+	}
+
+	repo := git.Repo{Dir: topLevel}
+
+	base, err := repo.MergeBase(ctx, current, other)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to find merge base of %s and %s: %w",
+			current,
+			other,
+			err,
+		)
+	}
+
+	slog.InfoContext(
+		ctx,
+		"merging go module files",
+		slog.String("current", current),
+		slog.String("other", other),
+		slog.String("merge-base", base),
+	)
+
+	modOptions, err := strategyOptions(mergeFlags.Strategies)
+	if err != nil {
+		return err
+	}
+
+	merge := newTreeMerge(mergeOptions{
+		labels:        markers.Labels{Current: current, Other: other},
+		modOptions:    modOptions,
+		syncGoSum:     *mergeFlags.SyncGoSum,
+		fillFromCache: *mergeFlags.FillFromCache,
+		verify:        *mergeFlags.Verify,
+		gosumdb:       *mergeFlags.GOSUMDB,
+	}, topLevel)
+
+	conflicted, insecure, err := merge.mergeCommits(ctx, repo, base, current, other)
+	if err != nil {
+		return err
+	}
+
+	if *mergeFlags.Write {
+		if err := merge.write(cmd.OutOrStdout(), topLevel); err != nil {
+			return err
+		}
+	} else {
+		merge.print(cmd.OutOrStdout())
+	}
+
+	return conflictedFilesError(conflicted, insecure)
+}
+
+// readCommitFile reads the file from the commit, whose files are given, and
+// reports whether the commit has the file.
+func readCommitFile(ctx context.Context, repo git.Repo, rev, file string, files []string) ([]byte, bool, error) {
+	if !slices.Contains(files, file) {
+		return nil, false, nil
+	}
+
+	data, err := repo.ReadCommit(ctx, rev, file)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s from %s: %w", file, rev, err)
+	}
+
+	return data, true, nil
+}
+
+// sides reports which sides of a merge have a file.
+type sides struct {
+	current bool
+	other   bool
+}
+
+// treeMerge merges the go module files of a tree together, so merged go.sum and
+// go.work.sum files can be kept consistent with the go.mod and go.work files
+// merged alongside them.
+type treeMerge struct {
+	opts mergeOptions
+
+	// topLevel is the top of the work tree, below which the config files
+	// governing each file are found.
+	topLevel string
+
+	// merged holds the contents of each merged file.
+	merged map[string][]byte
+
+	// deleted holds the files the merge deletes.
+	deleted map[string]bool
+
+	// read reads a file which isn't in the merge, such as a go.mod file
+	// already merged, reporting whether it exists. Files outside the merge
+	// are treated as missing if it is nil.
+	read func(ctx context.Context, file string) ([]byte, bool)
+}
+
+// newTreeMerge returns a tree merge of the work tree at the top-level
+// directory, merging files with the options and the config governing them.
+func newTreeMerge(opts mergeOptions, topLevel string) *treeMerge {
+	return &treeMerge{
+		opts:     opts,
+		topLevel: topLevel,
+		merged:   make(map[string][]byte),
+		deleted:  make(map[string]bool),
+	}
+}
+
+// mergeCommits merges the go module files changed between the merge base and
+// either of the current and other commits, returning the files whose merges
+// have conflicts, and whether any of those are security conflicts. Files no
+// side changed are left out of the merge, but read from the current commit by
+// the files merged alongside them.
+func (m *treeMerge) mergeCommits(ctx context.Context, repo git.Repo, base, current, other string) ([]string, bool, error) {
+	ancestorFiles, err := repo.CommitFiles(ctx, base)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list files of merge base: %w", err)
+	}
+
+	currentFiles, err := repo.CommitFiles(ctx, current)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list files of %s: %w", current, err)
+	}
+
+	otherFiles, err := repo.CommitFiles(ctx, other)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list files of %s: %w", other, err)
+	}
+
+	m.read = func(ctx context.Context, file string) ([]byte, bool) {
+		data, ok, err := readCommitFile(ctx, repo, current, file, currentFiles)
+
+		return data, ok && err == nil
+	}
+
+	var (
+		conflicted []string
+		insecure   bool
+	)
+
+	for _, file := range mergeOrder(append(slices.Clone(currentFiles), otherFiles...)) {
+		var (
+			files   versions
+			present sides
+		)
+
+		inAncestor := slices.Contains(ancestorFiles, file)
+
+		files.ancestor, _, err = readCommitFile(ctx, repo, base, file, ancestorFiles)
+		if err != nil {
+			return nil, false, err
+		}
+
+		files.current, present.current, err = readCommitFile(ctx, repo, current, file, currentFiles)
+		if err != nil {
+			return nil, false, err
+		}
+
+		files.other, present.other, err = readCommitFile(ctx, repo, other, file, otherFiles)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if inAncestor && present.current && present.other &&
+			bytes.Equal(files.current, files.ancestor) && bytes.Equal(files.other, files.ancestor) {
+			continue
+		}
+
+		err = m.merge(ctx, file, files, inAncestor, present)
+		if errors.Is(err, ErrConflict) {
+			conflicted = append(conflicted, file)
+			insecure = insecure || errors.Is(err, ErrSecurity)
+		} else if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return conflicted, insecure, nil
+}
+
+// merge merges the versions of the file, which the ancestor may have, and
+// either side may have deleted. Files changed on only one side take that side's
+// version, as git does without running the merge driver.
+//
+// Files deleted on one side and changed on the other keep the changed version,
+// and are reported as conflicts, like any file whose merge has conflicts.
+func (m *treeMerge) merge(ctx context.Context, file string, files versions, inAncestor bool, present sides) error {
+	switch {
+	case present.current && present.other:
+	case !inAncestor && present.current:
+		m.merged[file] = files.current
+
+		return nil
+	case !inAncestor && present.other:
+		m.merged[file] = files.other
+
+		return nil
+	case present.current && bytes.Equal(files.current, files.ancestor),
+		present.other && bytes.Equal(files.other, files.ancestor):
+		m.deleted[file] = true
+
+		return nil
+	case present.current:
+		m.merged[file] = files.current
+
+		slog.WarnContext(ctx, "file deleted on one side but changed on the other", slog.String("file", file))
+
+		return fmt.Errorf("%w: %s deleted on one side but changed on the other", ErrConflict, file)
+	default:
+		m.merged[file] = files.other
+
+		slog.WarnContext(ctx, "file deleted on one side but changed on the other", slog.String("file", file))
+
+		return fmt.Errorf("%w: %s deleted on one side but changed on the other", ErrConflict, file)
+	}
+
+	switch {
+	case bytes.Equal(files.current, files.other), bytes.Equal(files.other, files.ancestor):
+		m.merged[file] = files.current
+
+		return nil
+	case bytes.Equal(files.current, files.ancestor):
+		m.merged[file] = files.other
+
+		return nil
+	}
+
+	opts, err := m.options(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+
+	err = mergeFile(ctx, file, files, opts, &output)
+	if err != nil && !errors.Is(err, ErrConflict) {
+		return fmt.Errorf("failed to merge %s: %w", file, err)
+	}
+
+	m.merged[file] = output.Bytes()
+
+	return err
+}
+
+// options returns the options to merge the file with, configured by the config
+// governing it, reading the go.mod and go.work files next to it from those
+// already merged.
+func (m *treeMerge) options(ctx context.Context, file string) (mergeOptions, error) {
+	dir := path.Dir(file)
+
+	opts, err := configure(ctx, m.opts, filepath.Join(m.topLevel, filepath.FromSlash(dir)))
+	if err != nil {
+		return mergeOptions{}, err
+	}
+
+	opts.goMod = func(ctx context.Context) (*modfile.File, bool, error) {
+		goModPath := path.Join(dir, "go.mod")
+
+		data, ok := m.file(ctx, goModPath)
+		if !ok {
+			return nil, false, fmt.Errorf("%s does not exist after the merge", goModPath)
+		}
+
+		mod, err := gomod.ParseData(goModPath, data)
+
+		return mod, true, err
+	}
+
+	opts.workspaceSums = func(ctx context.Context) []gosum.GoSum {
+		goWorkPath := path.Join(dir, "go.work")
+
+		data, ok := m.file(ctx, goWorkPath)
+		if !ok {
+			return nil
+		}
+
+		work, err := gowork.ParseData(goWorkPath, data)
+		if err != nil {
+			slog.WarnContext(
+				ctx,
+				"not pruning go.work.sum against workspace modules",
+				slog.String("error", err.Error()),
+			)
+
+			return nil
+		}
+
+		var members []gosum.GoSum
+
+		for _, moduleDir := range gowork.ModuleDirs(*work, dir) {
+			data, ok := m.file(ctx, path.Join(filepath.ToSlash(moduleDir), "go.sum"))
+			if !ok {
+				continue
+			}
+
+			member, err := gosum.NewGoSum(bytes.NewReader(data))
+			if err != nil {
+				continue
+			}
+
+			members = append(members, member)
+		}
+
+		return members
+	}
+
+	return opts, nil
+}
+
+// file returns the contents of the file after the merge, and whether it exists.
+func (m *treeMerge) file(ctx context.Context, file string) ([]byte, bool) {
+	if data, ok := m.merged[file]; ok {
+		return data, true
+	}
+
+	if m.deleted[file] || m.read == nil {
+		return nil, false
+	}
+
+	return m.read(ctx, file)
+}
+
+// print writes each merged file, in path order, under a header naming it.
+func (m *treeMerge) print(w io.Writer) {
+	for _, file := range m.files() {
+		if m.deleted[file] {
+			fmt.Fprintf(w, "==> %s (deleted) <==\n", file)
+
+			continue
+		}
+
+		fmt.Fprintf(w, "==> %s <==\n", file)
+		w.Write(m.merged[file])
+	}
+}
+
+// write writes each merged file to the work tree at the top-level directory,
+// removing those the merge deletes.
+func (m *treeMerge) write(w io.Writer, topLevel string) error {
+	for _, file := range m.files() {
+		target := filepath.Join(topLevel, filepath.FromSlash(file))
+
+		if m.deleted[file] {
+			if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove merged file (%s): %w", file, err)
+			}
+
+			fmt.Fprintf(w, "Removed %s\n", file)
+
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory of merged file (%s): %w", file, err)
+		}
+
+		if err := os.WriteFile(target, m.merged[file], 0o644); err != nil {
+			return fmt.Errorf("failed to write merged file (%s): %w", file, err)
+		}
+
+		fmt.Fprintf(w, "Wrote %s\n", file)
+	}
+
+	return nil
+}
+
+// files returns the paths of the merged files, in sorted order.
+func (m *treeMerge) files() []string {
+	files := make([]string, 0, len(m.merged)+len(m.deleted))
+
+	for file := range m.merged {
+		files = append(files, file)
+	}
+
+	for file := range m.deleted {
+		files = append(files, file)
+	}
+
+	slices.Sort(files)
+
+	return files
+}
+
+// mergeable reports whether the file is a go module file the merge driver
+// merges.
+func mergeable(file string) bool {
+	switch path.Base(file) {
+	case "go.mod", "go.sum", "go.work", "go.work.sum":
+		return true
+	case "modules.txt":
+		return path.Base(path.Dir(file)) == "vendor"
+	default:
+		return false
+	}
+}
+
+// mergeOrder returns the mergeable files, without duplicates, in the order to
+// merge them: go.mod, go.work and modules.txt files first, then go.sum files,
+// which depend on the merged go.mod files, then go.work.sum files, which depend
+// on the merged go.work and go.sum files.
+func mergeOrder(files []string) []string {
+	rank := func(file string) int {
+		switch path.Base(file) {
+		case "go.sum":
+			return 1
+		case "go.work.sum":
+			return 2
+		default:
+			return 0
+		}
+	}
+
+	var order []string
+
+	for _, file := range files {
+		if mergeable(file) {
+			order = append(order, file)
+		}
+	}
+
+	slices.SortFunc(order, func(this, other string) int {
+		return cmp.Or(cmp.Compare(rank(this), rank(other)), strings.Compare(this, other))
+	})
+
+	return slices.Compact(order)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeMerge_mergeCommits(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := initRepo(t)
+
+	commitTree(t, repo, "base", map[string]string{
+		"go.mod":           ancestorGoMod,
+		"deleted/go.mod":   "module example.com/deleted\n",
+		"conflict/go.mod":  "module example.com/conflict\n",
+		"unchanged/go.mod": "module example.com/unchanged\n",
+	})
+
+	runGit(t, repo, "checkout", "--quiet", "-b", "other")
+	commitTree(t, repo, "other", map[string]string{
+		"go.mod":          otherGoMod,
+		"conflict/go.mod": "module example.com/conflict\n\ngo 1.22\n",
+	})
+
+	runGit(t, repo, "checkout", "--quiet", "-")
+	runGit(t, repo, "rm", "--quiet", "deleted/go.mod", "conflict/go.mod")
+	commitTree(t, repo, "current", map[string]string{
+		"go.mod":       currentGoMod,
+		"added/go.mod": "module example.com/added\n",
+	})
+
+	base, err := repo.MergeBase(ctx, "HEAD", "other")
+	require.NoError(t, err)
+
+	merge := newTreeMerge(mergeOptions{}, repo.Dir)
+
+	conflicted, insecure, err := merge.mergeCommits(ctx, repo, base, "HEAD", "other")
+	require.NoError(t, err)
+
+	// Files deleted on one side and changed on the other keep the changed
+	// version, as conflicts.
+	assert.Equal(t, []string{"conflict/go.mod"}, conflicted)
+	assert.False(t, insecure)
+
+	// Files no side changed are left out of the merge.
+	assert.Equal(
+		t,
+		[]string{"added/go.mod", "conflict/go.mod", "deleted/go.mod", "go.mod"},
+		merge.files(),
+	)
+
+	assert.Equal(t, "module example.com/added\n", string(merge.merged["added/go.mod"]))
+	assert.Equal(t, "module example.com/conflict\n\ngo 1.22\n", string(merge.merged["conflict/go.mod"]))
+	assert.True(t, merge.deleted["deleted/go.mod"])
+	assert.Equal(
+		t,
+		`module example.com/m
+
+go 1.22
+
+require (
+	example.com/a v1.1.0
+	example.com/b v1.0.0
+)
+`,
+		string(merge.merged["go.mod"]),
+	)
+
+	// But they can still be read by the files merged alongside them.
+	data, ok := merge.file(ctx, "unchanged/go.mod")
+	require.True(t, ok)
+
+	assert.Equal(t, "module example.com/unchanged\n", string(data))
+
+	var output bytes.Buffer
+
+	merge.print(&output)
+
+	assert.NotContains(t, output.String(), "unchanged/go.mod")
+	assert.Contains(t, output.String(), "==> deleted/go.mod (deleted) <==\n")
+}
+
+func TestTreeMerge_merge(t *testing.T) {
+	t.Parallel()
+
+	ancestor := []byte("module example.com/m\n")
+	changed := []byte("module example.com/m\n\ngo 1.22\n")
+
+	testCases := []struct {
+		name        string
+		files       versions
+		inAncestor  bool
+		present     sides
+		wantMerged  []byte
+		wantDeleted bool
+		wantErr     error
+	}{
+		{
+			name:       "added on current",
+			files:      versions{current: changed},
+			present:    sides{current: true},
+			wantMerged: changed,
+		},
+		{
+			name:       "added on other",
+			files:      versions{other: changed},
+			present:    sides{other: true},
+			wantMerged: changed,
+		},
+		{
+			name:        "deleted on other, unchanged on current",
+			files:       versions{ancestor: ancestor, current: ancestor},
+			inAncestor:  true,
+			present:     sides{current: true},
+			wantDeleted: true,
+		},
+		{
+			name:       "deleted on current, changed on other",
+			files:      versions{ancestor: ancestor, other: changed},
+			inAncestor: true,
+			present:    sides{other: true},
+			wantMerged: changed,
+			wantErr:    ErrConflict,
+		},
+		{
+			name:       "changed on current",
+			files:      versions{ancestor: ancestor, current: changed, other: ancestor},
+			inAncestor: true,
+			present:    sides{current: true, other: true},
+			wantMerged: changed,
+		},
+		{
+			name:       "changed on other",
+			files:      versions{ancestor: ancestor, current: ancestor, other: changed},
+			inAncestor: true,
+			present:    sides{current: true, other: true},
+			wantMerged: changed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			merge := newTreeMerge(mergeOptions{}, t.TempDir())
+
+			err := merge.merge(context.Background(), "go.mod", tc.files, tc.inAncestor, tc.present)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.wantMerged, merge.merged["go.mod"])
+			assert.Equal(t, tc.wantDeleted, merge.deleted["go.mod"])
+		})
+	}
+}
+
+// commitTree writes the files, at paths relative to the top of the work tree,
+// to the repository and commits them, along with any changes already staged.
+func commitTree(t *testing.T, repo git.Repo, message string, files map[string]string) {
+	t.Helper()
+
+	for file, contents := range files {
+		target := filepath.Join(repo.Dir, filepath.FromSlash(file))
+
+		require.NoError(t, os.MkdirAll(filepath.Dir(target), 0o755))
+		require.NoError(t, os.WriteFile(target, []byte(contents), 0o644))
+
+		runGit(t, repo, "add", file)
+	}
+
+	runGit(t, repo, "commit", "--quiet", "-m", message)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/crystalix007/go-merge-drivers/internal/flags"
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+	"github.com/spf13/cobra"
+)
+
+// The stages of the index holding each version of an unmerged file.
+const (
+	stageAncestor = 1
+	stageCurrent  = 2
+	stageOther    = 3
+)
+
+// newResolveCmd returns the command resolving unmerged go module files.
+func newResolveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resolve",
+		Short: "Resolve unmerged go module files in the work tree",
+		Long: "Merges every unmerged go.mod, go.sum, go.work, go.work.sum and vendor/modules.txt " +
+			"file from the versions git left in the index, such as after a merge, rebase or " +
+			"cherry-pick without the merge driver, and writes the merged files to the work tree.",
+		Args: cobra.NoArgs,
+	}
+
+	resolveFlags := flags.AddResolveFlags(cmd)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return runResolve(cmd, resolveFlags)
+	}
+
+	return cmd
+}
+
+// runResolve merges the unmerged go module files from the index, writing them
+// to the work tree.
+func runResolve(cmd *cobra.Command, resolveFlags flags.ResolveFlags) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+
+	if *resolveFlags.FillFromCache && !*resolveFlags.SyncGoSum {
+		return ErrFillNeedsSync
+	}
+
+	topLevel, err := git.Repo{}.TopLevel(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotInRepo, err)
+	}
+
+	modOptions, err := strategyOptions(resolveFlags.Strategies)
+	if err != nil {
+		return err
+	}
+
+	opts := mergeOptions{
+		labels:        markers.DefaultLabels,
+		modOptions:    modOptions,
+		syncGoSum:     *resolveFlags.SyncGoSum,
+		fillFromCache: *resolveFlags.FillFromCache,
+		verify:        *resolveFlags.Verify,
+		gosumdb:       *resolveFlags.GOSUMDB,
+	}
+
+	return resolve(ctx, out, git.Repo{Dir: topLevel}, opts, *resolveFlags.Add)
+}
+
+// resolve merges the unmerged go module files of the repository, whose
+// directory is the top of its work tree, from the index, writing them to the
+// work tree. If add is set, the files merged without conflicts are added to the
+// index.
+func resolve(ctx context.Context, out io.Writer, repo git.Repo, opts mergeOptions, add bool) error {
+	unmerged, err := repo.UnmergedFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list unmerged files: %w", err)
+	}
+
+	stages := make(map[string][]int, len(unmerged))
+
+	for _, file := range unmerged {
+		stages[file.Path] = file.Stages
+	}
+
+	files := mergeOrder(slices.Collect(maps.Keys(stages)))
+
+	if len(files) == 0 {
+		fmt.Fprintln(out, "No unmerged go module files")
+
+		return nil
+	}
+
+	merge := newTreeMerge(opts, repo.Dir)
+
+	// Files which aren't unmerged are already merged in the index.
+	merge.read = func(ctx context.Context, file string) ([]byte, bool) {
+		data, err := repo.ReadIndex(ctx, 0, file)
+
+		return data, err == nil
+	}
+
+	var (
+		resolved, conflicted []string
+		insecure             bool
+	)
+
+	for _, file := range files {
+		versions, present, err := readIndexVersions(ctx, repo, file, stages[file])
+		if err != nil {
+			return err
+		}
+
+		err = merge.merge(ctx, file, versions, slices.Contains(stages[file], stageAncestor), present)
+
+		switch {
+		case errors.Is(err, ErrConflict):
+			conflicted = append(conflicted, file)
+			insecure = insecure || errors.Is(err, ErrSecurity)
+		case err != nil:
+			return err
+		default:
+			resolved = append(resolved, file)
+		}
+	}
+
+	if err := merge.write(out, repo.Dir); err != nil {
+		return err
+	}
+
+	if add && len(resolved) > 0 {
+		if err := repo.Add(ctx, resolved...); err != nil {
+			return fmt.Errorf("failed to add resolved files: %w", err)
+		}
+
+		slog.InfoContext(ctx, "added resolved files", slog.Int("files", len(resolved)))
+
+		for _, file := range resolved {
+			fmt.Fprintf(out, "Added %s\n", file)
+		}
+	}
+
+	return conflictedFilesError(conflicted, insecure)
+}
+
+// readIndexVersions reads the versions of the unmerged file from the stages of
+// the index holding it, and which of the current and other versions have the
+// file.
+func readIndexVersions(ctx context.Context, repo git.Repo, file string, stages []int) (versions, sides, error) {
+	var (
+		files   versions
+		present sides
+	)
+
+	for _, stage := range stages {
+		data, err := repo.ReadIndex(ctx, stage, file)
+		if err != nil {
+			return versions{}, sides{}, fmt.Errorf(
+				"failed to read %s from stage %d of the index: %w",
+				file,
+				stage,
+				err,
+			)
+		}
+
+		switch stage {
+		case stageAncestor:
+			files.ancestor = data
+		case stageCurrent:
+			files.current = data
+			present.current = true
+		case stageOther:
+			files.other = data
+			present.other = true
+		}
+	}
+
+	return files, present, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := initConflictedRepo(t)
+
+	var output bytes.Buffer
+
+	err := resolve(ctx, &output, repo, mergeOptions{}, true)
+
+	// Files deleted on one side and changed on the other are still conflicts.
+	require.ErrorIs(t, err, ErrConflict)
+	assert.Contains(t, err.Error(), "deleted/go.mod")
+
+	assert.Equal(
+		t,
+		"Wrote deleted/go.mod\nWrote go.mod\nWrote go.sum\nAdded go.mod\nAdded go.sum\n",
+		output.String(),
+	)
+
+	assert.Equal(
+		t,
+		`module example.com/m
+
+go 1.22
+
+require (
+	example.com/a v1.1.0
+	example.com/b v1.0.0
+)
+`,
+		readFile(t, repo, "go.mod"),
+	)
+	assert.Equal(t, otherGoSum+"example.com/b v1.0.0 h1:b100=\nexample.com/b v1.0.0/go.mod h1:b100mod=\n", readFile(t, repo, "go.sum"))
+	assert.Equal(t, "module example.com/deleted\n\ngo 1.22\n", readFile(t, repo, "deleted/go.mod"))
+
+	// Only the files merged without conflicts are added to the index.
+	unmerged, err := repo.UnmergedFiles(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, unmerged, 1)
+	assert.Equal(t, "deleted/go.mod", unmerged[0].Path)
+}
+
+func TestResolve_withoutAdd(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := initConflictedRepo(t)
+
+	var output bytes.Buffer
+
+	err := resolve(ctx, &output, repo, mergeOptions{}, false)
+	require.ErrorIs(t, err, ErrConflict)
+
+	assert.NotContains(t, output.String(), "Added")
+
+	unmerged, err := repo.UnmergedFiles(ctx)
+	require.NoError(t, err)
+
+	assert.Len(t, unmerged, 3)
+}
+
+func TestResolve_nothingUnmerged(t *testing.T) {
+	t.Parallel()
+
+	repo := initMergeRepo(t)
+
+	var output bytes.Buffer
+
+	require.NoError(t, resolve(context.Background(), &output, repo, mergeOptions{}, true))
+
+	assert.Equal(t, "No unmerged go module files\n", output.String())
+}
+
+func TestReadIndexVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := initConflictedRepo(t)
+
+	testCases := []struct {
+		file     string
+		stages   []int
+		expected versions
+		present  sides
+	}{
+		{
+			file:   "go.mod",
+			stages: []int{stageAncestor, stageCurrent, stageOther},
+			expected: versions{
+				ancestor: []byte(ancestorGoMod),
+				current:  []byte(currentGoMod),
+				other:    []byte(otherGoMod),
+			},
+			present: sides{current: true, other: true},
+		},
+		{
+			// Deleted on the current side, so missing from its stage.
+			file:   "deleted/go.mod",
+			stages: []int{stageAncestor, stageOther},
+			expected: versions{
+				ancestor: []byte("module example.com/deleted\n"),
+				other:    []byte("module example.com/deleted\n\ngo 1.22\n"),
+			},
+			present: sides{other: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			t.Parallel()
+
+			files, present, err := readIndexVersions(ctx, repo, tc.file, tc.stages)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, files)
+			assert.Equal(t, tc.present, present)
+		})
+	}
+
+	_, _, err := readIndexVersions(ctx, repo, "go.mod", []int{4})
+	require.Error(t, err)
+}
+
+// initConflictedRepo creates a repository part way through a merge without the
+// merge driver, with conflicts in go.mod and go.sum, and in deleted/go.mod,
+// which the checked out branch deleted and the other branch changed.
+func initConflictedRepo(t *testing.T) git.Repo {
+	t.Helper()
+
+	repo := initRepo(t)
+
+	commitTree(t, repo, "base", map[string]string{
+		"go.mod":         ancestorGoMod,
+		"go.sum":         ancestorGoSum,
+		"deleted/go.mod": "module example.com/deleted\n",
+	})
+
+	runGit(t, repo, "checkout", "--quiet", "-b", "other")
+	commitTree(t, repo, "bump a", map[string]string{
+		"go.mod":         otherGoMod,
+		"go.sum":         otherGoSum,
+		"deleted/go.mod": "module example.com/deleted\n\ngo 1.22\n",
+	})
+
+	runGit(t, repo, "checkout", "--quiet", "-")
+	runGit(t, repo, "rm", "--quiet", "deleted/go.mod")
+	commitTree(t, repo, "add b", map[string]string{
+		"go.mod": currentGoMod,
+		"go.sum": currentGoSum,
+	})
+
+	// The merge stops with conflicts, so fails.
+	cmd := exec.Command("git", "merge", "--quiet", "other")
+	cmd.Dir = repo.Dir
+
+	output, err := cmd.CombinedOutput()
+	require.Error(t, err, string(output))
+
+	return repo
+}
+
+// readFile reads the file, at a path relative to the top of the work tree, from
+// the repository's work tree.
+func readFile(t *testing.T, repo git.Repo, file string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(repo.Dir, filepath.FromSlash(file)))
+	require.NoError(t, err)
+
+	return string(data)
+}
@@ -0,0 +1,311 @@
+// Package config reads the repository configuration of the merge driver, from
+// a .go-merge.yaml or .go-merge.toml file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the names of the config files, in the order they are looked for
+// in each directory.
+var FileNames = []string{".go-merge.yaml", ".go-merge.yml", ".go-merge.toml"}
+
+var (
+	// ErrInvalid is returned when a config file is malformed, or holds invalid
+	// settings.
+	ErrInvalid = errors.New("config: invalid config")
+
+	// ErrAmbiguous is returned when a directory has more than one config file.
+	ErrAmbiguous = errors.New("config: more than one config file")
+)
+
+// Config is the repository configuration of the merge driver.
+type Config struct {
+	// Strategy is the name of the version selection strategy used for
+	// modules no rule in Strategies matches.
+	Strategy string `yaml:"strategy" toml:"strategy"`
+
+	// Strategies select the strategy used for the modules matching each
+	// pattern, in order of precedence.
+	Strategies []StrategyRule `yaml:"strategies" toml:"strategies"`
+
+	// PseudoVersions is the name of the rule deciding how pseudo-versions
+	// compare to tagged versions.
+	PseudoVersions string `yaml:"pseudo-versions" toml:"pseudo-versions"`
+
+	// Pinned are patterns of modules whose requirements and replacements a
+	// merge never changes.
+	Pinned []string `yaml:"pinned" toml:"pinned"`
+
+	// Protected are patterns of modules whose requirements and replacements
+	// a merge never merges, reporting any change to them as a conflict.
+	Protected []string `yaml:"protected" toml:"protected"`
+
+	// Denied are module versions a merge never selects.
+	Denied []Denied `yaml:"denied" toml:"denied"`
+
+	// GoSum configures how go.sum files are merged.
+	GoSum GoSum `yaml:"go-sum" toml:"go-sum"`
+}
+
+// StrategyRule selects a version selection strategy for the modules matching a
+// pattern.
+type StrategyRule struct {
+	// Pattern is a glob pattern matching module path prefixes, in the same
+	// form as the GOPRIVATE environment variable.
+	Pattern string `yaml:"pattern" toml:"pattern"`
+
+	// Strategy is the name of the strategy.
+	Strategy string `yaml:"strategy" toml:"strategy"`
+}
+
+// Denied lists versions of a module a merge never selects.
+type Denied struct {
+	// Module is the module path.
+	Module string `yaml:"module" toml:"module"`
+
+	// Versions are the denied versions of the module.
+	Versions []string `yaml:"versions" toml:"versions"`
+}
+
+// GoSum configures how go.sum files are merged.
+type GoSum struct {
+	// Prune keeps merged go.sum files consistent with the merged go.mod
+	// files, pruning hashes of module versions they no longer reach.
+	Prune bool `yaml:"prune" toml:"prune"`
+
+	// Verify verifies new go.sum hashes against the checksum database.
+	Verify bool `yaml:"verify" toml:"verify"`
+}
+
+// Find finds the config file governing the directory, walking up from it to the
+// top of the repository's work tree, and reads it. The returned path is empty,
+// and the config zero, if there is no config file.
+func Find(dir string) (Config, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return Config{}, "", fmt.Errorf("failed to find config file: %w", err)
+	}
+
+	for {
+		file, err := find(dir)
+		if err != nil {
+			return Config{}, "", err
+		}
+
+		if file != "" {
+			config, err := Load(file)
+
+			return config, file, err
+		}
+
+		// The top of the work tree holds the .git directory, or a .git file
+		// in a linked work tree or submodule.
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return Config{}, "", nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return Config{}, "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// find returns the path of the config file in the directory, or an empty path
+// if it has none.
+func find(dir string) (string, error) {
+	var found []string
+
+	for _, name := range FileNames {
+		file := filepath.Join(dir, name)
+
+		_, err := os.Stat(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to find config file: %w", err)
+		}
+
+		found = append(found, file)
+	}
+
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrAmbiguous, strings.Join(found, ", "))
+	}
+}
+
+// Load reads and validates the config file, decoding it as TOML if its name
+// ends in .toml, and YAML otherwise.
+func Load(file string) (Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file (%s): %w", file, err)
+	}
+
+	var config Config
+
+	if filepath.Ext(file) == ".toml" {
+		config, err = decodeTOML(data)
+	} else {
+		config, err = decodeYAML(data)
+	}
+
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w: %w", file, ErrInvalid, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return config, nil
+}
+
+// decodeYAML decodes a YAML config, rejecting unknown settings.
+func decodeYAML(data []byte) (Config, error) {
+	var config Config
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	// An empty file decodes as io.EOF, and is an empty config.
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// decodeTOML decodes a TOML config, rejecting unknown settings.
+func decodeTOML(data []byte) (Config, error) {
+	var config Config
+
+	meta, err := toml.Decode(string(data), &config)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+
+		return Config{}, fmt.Errorf("unknown settings: %s", strings.Join(keys, ", "))
+	}
+
+	return config, nil
+}
+
+// Validate checks the config's settings, naming the first invalid one.
+func (c Config) Validate() error {
+	_, err := c.ModOptions()
+
+	return err
+}
+
+// ModOptions returns the go.mod merge options the config selects.
+func (c Config) ModOptions() (gomod.Options, error) {
+	var opts gomod.Options
+
+	if c.Strategy != "" {
+		strategy, err := gomod.ParseStrategy(c.Strategy)
+		if err != nil {
+			return gomod.Options{}, fmt.Errorf("%w: strategy: %w", ErrInvalid, err)
+		}
+
+		opts.DefaultStrategy = strategy
+	}
+
+	if c.PseudoVersions != "" {
+		rule, err := gomod.ParsePseudoRule(c.PseudoVersions)
+		if err != nil {
+			return gomod.Options{}, fmt.Errorf("%w: pseudo-versions: %w", ErrInvalid, err)
+		}
+
+		opts.Pseudo = rule
+	}
+
+	for i, rule := range c.Strategies {
+		if rule.Pattern == "" {
+			return gomod.Options{}, fmt.Errorf("%w: strategies[%d]: pattern must be set", ErrInvalid, i)
+		}
+
+		strategy, err := gomod.ParseStrategy(rule.Strategy)
+		if err != nil {
+			return gomod.Options{}, fmt.Errorf("%w: strategies[%d]: %w", ErrInvalid, i, err)
+		}
+
+		opts.Strategies = append(opts.Strategies, gomod.StrategyRule{
+			Pattern:  rule.Pattern,
+			Strategy: strategy,
+		})
+	}
+
+	for i, pattern := range c.Pinned {
+		if pattern == "" {
+			return gomod.Options{}, fmt.Errorf("%w: pinned[%d]: pattern must be set", ErrInvalid, i)
+		}
+	}
+
+	for i, pattern := range c.Protected {
+		if pattern == "" {
+			return gomod.Options{}, fmt.Errorf("%w: protected[%d]: pattern must be set", ErrInvalid, i)
+		}
+	}
+
+	opts.Pinned = c.Pinned
+	opts.Protected = c.Protected
+
+	for i, denied := range c.Denied {
+		if err := module.CheckPath(denied.Module); err != nil {
+			return gomod.Options{}, fmt.Errorf("%w: denied[%d]: %w", ErrInvalid, i, err)
+		}
+
+		if len(denied.Versions) == 0 {
+			return gomod.Options{}, fmt.Errorf("%w: denied[%d]: versions must be set", ErrInvalid, i)
+		}
+
+		for _, version := range denied.Versions {
+			if !semver.IsValid(version) || semver.Canonical(version) != strings.TrimSuffix(version, "+incompatible") {
+				return gomod.Options{}, fmt.Errorf(
+					"%w: denied[%d]: %q is not a canonical semantic version, such as v1.2.3",
+					ErrInvalid,
+					i,
+					version,
+				)
+			}
+
+			opts.Denied = append(opts.Denied, module.Version{
+				Path:    denied.Module,
+				Version: version,
+			})
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,156 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/config"
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+)
+
+// expected is the config held by the files in testdata.
+var expected = config.Config{
+	Strategy:       "higher",
+	PseudoVersions: "prefer-release",
+	Strategies: []config.StrategyRule{
+		{Pattern: "example.com/regulated/*", Strategy: "fail-on-divergent"},
+	},
+	Pinned:    []string{"example.com/platform/sdk"},
+	Protected: []string{"golang.org/x/crypto", "example.com/auth"},
+	Denied: []config.Denied{
+		{Module: "example.com/dep", Versions: []string{"v1.2.0", "v1.2.1"}},
+	},
+	GoSum: config.GoSum{
+		Prune:  true,
+		Verify: true,
+	},
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	for _, file := range []string{"testdata/config.yaml", "testdata/config.toml"} {
+		t.Run(filepath.Ext(file), func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := config.Load(file)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, cfg)
+		})
+	}
+}
+
+func TestLoad_invalid(t *testing.T) {
+	t.Parallel()
+
+	for name, contents := range map[string]string{
+		".go-merge.yaml":  "strategy: newest\n",
+		".go-merge.yml":   "strategies:\n  - strategy: lower\n",
+		".go-merge.toml":  "[[denied]]\nmodule = \"example.com/dep\"\nversions = [\"1.2.0\"]\n",
+		"unknown.yaml":    "pined: [example.com/dep]\n",
+		"unknown.toml":    "[go-sum]\npurne = true\n",
+		"malformed.yaml":  "strategy: [higher\n",
+		"denied.toml":     "[[denied]]\nmodule = \"example.com/dep\"\n",
+		"badmodule.yaml":  "denied:\n  - module: Example..com\n    versions: [v1.0.0]\n",
+		"emptypin.yaml":   "pinned: ['']\n",
+		"emptyprot.toml":  "protected = [\"\"]\n",
+		"pseudo.yaml":     "pseudo-versions: newest\n",
+		"badstrategy.yml": "strategies:\n  - pattern: example.com\n    strategy: newest\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			file := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(file, []byte(contents), 0o644))
+
+			_, err := config.Load(file)
+			require.ErrorIs(t, err, config.ErrInvalid)
+
+			// Errors name the file.
+			assert.Contains(t, err.Error(), file)
+		})
+	}
+}
+
+func TestLoad_empty(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), ".go-merge.yaml")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	cfg, err := config.Load(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, config.Config{}, cfg)
+}
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	dir := filepath.Join(repo, "services", "api")
+
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0o755))
+
+	// Config files outside the repository are ignored.
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".go-merge.yaml"), []byte("strategy: lower\n"), 0o644))
+
+	cfg, file, err := config.Find(dir)
+	require.NoError(t, err)
+
+	assert.Empty(t, file)
+	assert.Equal(t, config.Config{}, cfg)
+
+	data, err := os.ReadFile("testdata/config.toml")
+	require.NoError(t, err)
+
+	expectedFile := filepath.Join(repo, ".go-merge.toml")
+	require.NoError(t, os.WriteFile(expectedFile, data, 0o644))
+
+	cfg, file, err = config.Find(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, expectedFile, file)
+	assert.Equal(t, expected, cfg)
+}
+
+func TestFind_ambiguous(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".go-merge.yaml"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".go-merge.toml"), nil, 0o644))
+
+	_, _, err := config.Find(dir)
+	require.ErrorIs(t, err, config.ErrAmbiguous)
+}
+
+func TestConfig_ModOptions(t *testing.T) {
+	t.Parallel()
+
+	opts, err := expected.ModOptions()
+	require.NoError(t, err)
+
+	assert.Equal(t, gomod.PseudoPreferRelease, opts.Pseudo)
+
+	require.Len(t, opts.Strategies, 1)
+	assert.Equal(t, "example.com/regulated/*", opts.Strategies[0].Pattern)
+
+	// The rule fails on divergent versions.
+	_, err = opts.Strategies[0].Strategy.Select("example.com/regulated/dep", "v1.2.0", "v1.3.0", "v1.1.0")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"example.com/platform/sdk"}, opts.Pinned)
+	assert.Equal(t, []string{"golang.org/x/crypto", "example.com/auth"}, opts.Protected)
+	assert.Equal(t, []module.Version{
+		{Path: "example.com/dep", Version: "v1.2.0"},
+		{Path: "example.com/dep", Version: "v1.2.1"},
+	}, opts.Denied)
+}
@@ -8,6 +8,12 @@ type Flags struct {
 	OtherVersion   *string
 	Result         *string
 	Output         *string
+	Labels         *[]string
+	GoMod          *string
+	FillFromCache  *bool
+	Verify         *bool
+	GOSUMDB        *string
+	Strategies     StrategyFlags
 }
 
 func AddFlags(cmd *cobra.Command) Flags {
@@ -19,5 +25,99 @@ func AddFlags(cmd *cobra.Command) Flags {
 		OtherVersion:   flags.StringP("other-version", "B", "", "Other version file"),
 		Result:         flags.StringP("result", "P", "", "Result file"),
 		Output:         flags.String("output", "/dev/stdout", "Output file"),
+		Labels:         flags.StringArrayP("label", "L", nil, "Conflict marker labels (current, ancestor, other)"),
+		GoMod:          flags.String("go-mod", "", "Sync go.sum with the go.mod merged from the merge's commits, or else read from the worktree or index"),
+		FillFromCache:  flags.Bool("fill-from-cache", false, "Add missing go.sum hashes from the module cache (needs --go-mod)"),
+		Verify:         flags.Bool("verify", false, "Verify new go.sum hashes against the checksum database"),
+		GOSUMDB:        flags.String("gosumdb", "", "Checksum database to verify against, as in GOSUMDB (default $GOSUMDB)"),
+		Strategies:     AddStrategyFlags(cmd),
+	}
+}
+
+type StrategyFlags struct {
+	Strategy         *string
+	ModuleStrategies *[]string
+	PseudoVersions   *string
+}
+
+func AddStrategyFlags(cmd *cobra.Command) StrategyFlags {
+	flags := cmd.Flags()
+
+	return StrategyFlags{
+		Strategy:         flags.String("strategy", "", "Version selection strategy for requires changed on both sides (default higher)"),
+		ModuleStrategies: flags.StringArray("module-strategy", nil, "Strategy for modules matching a path glob, as pattern=strategy"),
+		PseudoVersions:   flags.String("pseudo-versions", "", "How pseudo-versions compare to tagged versions: semver, prefer-release or prefer-pseudo (default semver)"),
+	}
+}
+
+type InstallFlags struct {
+	Scope  *string
+	Binary *string
+	GoRun  *bool
+}
+
+func AddInstallFlags(cmd *cobra.Command) InstallFlags {
+	flags := cmd.Flags()
+
+	return InstallFlags{
+		Scope:  flags.String("scope", "repo", "Git config to install the driver into (repo, global or system)"),
+		Binary: flags.String("binary", "", "Path of the driver binary to run (default this binary)"),
+		GoRun:  flags.Bool("go-run", false, "Run the driver with go run instead of a local binary"),
+	}
+}
+
+type UninstallFlags struct {
+	Scope *string
+}
+
+func AddUninstallFlags(cmd *cobra.Command) UninstallFlags {
+	flags := cmd.Flags()
+
+	return UninstallFlags{
+		Scope: flags.String("scope", "repo", "Git config to uninstall the driver from (repo, global or system)"),
+	}
+}
+
+type MergeFlags struct {
+	Write         *bool
+	SyncGoSum     *bool
+	FillFromCache *bool
+	Verify        *bool
+	GOSUMDB       *string
+	Strategies    StrategyFlags
+}
+
+func AddMergeFlags(cmd *cobra.Command) MergeFlags {
+	flags := cmd.Flags()
+
+	return MergeFlags{
+		Write:         flags.BoolP("write", "w", false, "Write the merged files to the work tree instead of printing them"),
+		SyncGoSum:     flags.Bool("sync-go-sum", false, "Keep merged go.sum files consistent with the merged go.mod files"),
+		FillFromCache: flags.Bool("fill-from-cache", false, "Add missing go.sum hashes from the module cache (needs --sync-go-sum)"),
+		Verify:        flags.Bool("verify", false, "Verify new go.sum hashes against the checksum database"),
+		GOSUMDB:       flags.String("gosumdb", "", "Checksum database to verify against, as in GOSUMDB (default $GOSUMDB)"),
+		Strategies:    AddStrategyFlags(cmd),
+	}
+}
+
+type ResolveFlags struct {
+	Add           *bool
+	SyncGoSum     *bool
+	FillFromCache *bool
+	Verify        *bool
+	GOSUMDB       *string
+	Strategies    StrategyFlags
+}
+
+func AddResolveFlags(cmd *cobra.Command) ResolveFlags {
+	flags := cmd.Flags()
+
+	return ResolveFlags{
+		Add:           flags.Bool("add", false, "Add the files merged without conflicts to the index"),
+		SyncGoSum:     flags.Bool("sync-go-sum", false, "Keep merged go.sum files consistent with the merged go.mod files"),
+		FillFromCache: flags.Bool("fill-from-cache", false, "Add missing go.sum hashes from the module cache (needs --sync-go-sum)"),
+		Verify:        flags.Bool("verify", false, "Verify new go.sum hashes against the checksum database"),
+		GOSUMDB:       flags.String("gosumdb", "", "Checksum database to verify against, as in GOSUMDB (default $GOSUMDB)"),
+		Strategies:    AddStrategyFlags(cmd),
 	}
 }
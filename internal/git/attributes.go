@@ -0,0 +1,136 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// AddAttributes adds a line setting the attribute for each pattern to the
+// gitattributes file, creating it if needed. The lines are added under a
+// comment line holding the marker, so [RemoveAttributes] can tell them apart
+// from lines added by hand. Patterns which already have the line are left
+// alone, so adding the same attributes again changes nothing. It returns the
+// patterns which were added.
+func AddAttributes(file string, patterns []string, attribute, marker string) ([]string, error) {
+	lines, err := readAttributes(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []string
+
+	for _, pattern := range patterns {
+		if !slices.ContainsFunc(lines, isAttributeLine(pattern, attribute)) {
+			added = append(added, pattern)
+		}
+	}
+
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	lines = append(lines, "# "+marker)
+
+	for _, pattern := range added {
+		lines = append(lines, pattern+" "+attribute)
+	}
+
+	return added, writeAttributes(file, lines)
+}
+
+// RemoveAttributes removes the lines setting the attribute for each pattern
+// which [AddAttributes] added under a comment line holding the marker, along
+// with the comment line, from the gitattributes file, removing the file if
+// nothing else is left in it. Lines added any other way are kept. It returns
+// the patterns which were removed.
+func RemoveAttributes(file string, patterns []string, attribute, marker string) ([]string, error) {
+	lines, err := readAttributes(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		kept    []string
+		removed []string
+		marked  bool
+	)
+
+	for _, line := range lines {
+		if line == "# "+marker {
+			marked = true
+
+			continue
+		}
+
+		if marked {
+			index := slices.IndexFunc(patterns, func(pattern string) bool {
+				return isAttributeLine(pattern, attribute)(line)
+			})
+			if index >= 0 {
+				removed = append(removed, patterns[index])
+
+				continue
+			}
+		}
+
+		// The marked lines end at the first line added another way.
+		marked = false
+
+		kept = append(kept, line)
+	}
+
+	if len(kept) == len(lines) {
+		return nil, nil
+	}
+
+	if len(kept) == 0 {
+		if err := os.Remove(file); err != nil {
+			return nil, fmt.Errorf("failed to remove gitattributes file (%s): %w", file, err)
+		}
+
+		return removed, nil
+	}
+
+	return removed, writeAttributes(file, kept)
+}
+
+// isAttributeLine returns a function reporting whether a gitattributes line
+// sets only the attribute for the pattern.
+func isAttributeLine(pattern, attribute string) func(string) bool {
+	return func(line string) bool {
+		return slices.Equal(strings.Fields(line), []string{pattern, attribute})
+	}
+}
+
+// readAttributes reads the lines of the gitattributes file, which may not
+// exist.
+func readAttributes(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gitattributes file (%s): %w", file, err)
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
+
+// writeAttributes writes the lines of the gitattributes file.
+func writeAttributes(file string, lines []string) error {
+	data := strings.Join(lines, "\n") + "\n"
+
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		return fmt.Errorf("failed to write gitattributes file (%s): %w", file, err)
+	}
+
+	return nil
+}
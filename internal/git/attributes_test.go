@@ -0,0 +1,106 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAttributes(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), ".gitattributes")
+
+	require.NoError(t, os.WriteFile(file, []byte("*.png binary\ngo.sum   merge=go\n"), 0o644))
+
+	added, err := git.AddAttributes(file, []string{"go.mod", "go.sum"}, "merge=go", marker)
+	require.NoError(t, err)
+
+	// go.sum already uses the attribute, with different spacing.
+	assert.Equal(t, []string{"go.mod"}, added)
+
+	contents, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, "*.png binary\ngo.sum   merge=go\n# "+marker+"\ngo.mod merge=go\n", string(contents))
+
+	// Adding the attributes again changes nothing.
+	added, err = git.AddAttributes(file, []string{"go.mod", "go.sum"}, "merge=go", marker)
+	require.NoError(t, err)
+
+	assert.Empty(t, added)
+}
+
+func TestAddAttributes_newFile(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), ".gitattributes")
+
+	added, err := git.AddAttributes(file, []string{"go.mod", "go.sum"}, "merge=go", marker)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"go.mod", "go.sum"}, added)
+
+	contents, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, "# "+marker+"\ngo.mod merge=go\ngo.sum merge=go\n", string(contents))
+}
+
+func TestRemoveAttributes(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), ".gitattributes")
+
+	require.NoError(t, os.WriteFile(file, []byte("go.sum merge=go\n*.png binary\n"), 0o644))
+
+	_, err := git.AddAttributes(file, []string{"go.mod", "go.sum"}, "merge=go", marker)
+	require.NoError(t, err)
+
+	// Lines added after the marked lines by hand aren't marked.
+	contents, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	contents = append(contents, "go.mod merge=go -diff\ngo.work merge=go\n"...)
+
+	require.NoError(t, os.WriteFile(file, contents, 0o644))
+
+	removed, err := git.RemoveAttributes(file, []string{"go.mod", "go.sum", "go.work"}, "merge=go", marker)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"go.mod"}, removed)
+
+	// Lines added by hand, and lines setting other attributes, are kept.
+	contents, err = os.ReadFile(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, "go.sum merge=go\n*.png binary\ngo.mod merge=go -diff\ngo.work merge=go\n", string(contents))
+}
+
+func TestRemoveAttributes_emptiesFile(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), ".gitattributes")
+
+	_, err := git.AddAttributes(file, []string{"go.mod", "go.sum"}, "merge=go", marker)
+	require.NoError(t, err)
+
+	removed, err := git.RemoveAttributes(file, []string{"go.mod", "go.sum"}, "merge=go", marker)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"go.mod", "go.sum"}, removed)
+	assert.NoFileExists(t, file)
+
+	// Removing from a missing file is a no-op.
+	removed, err = git.RemoveAttributes(file, []string{"go.mod"}, "merge=go", marker)
+	require.NoError(t, err)
+
+	assert.Empty(t, removed)
+}
+
+// marker marks the gitattributes lines added by the tests.
+const marker = "added by test"
@@ -0,0 +1,113 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrInvalidScope is returned when a git config scope is not one of the
+// supported scopes.
+var ErrInvalidScope = errors.New("git: config scope must be repo, global or system")
+
+// Scope is the git config file a setting is read from or written to.
+type Scope string
+
+const (
+	// ScopeRepo is the config file of the repository, .git/config.
+	ScopeRepo Scope = "repo"
+
+	// ScopeGlobal is the config file of the user, such as ~/.gitconfig.
+	ScopeGlobal Scope = "global"
+
+	// ScopeSystem is the config file of the system, such as /etc/gitconfig.
+	ScopeSystem Scope = "system"
+)
+
+// ParseScope parses the name of a git config scope.
+func ParseScope(name string) (Scope, error) {
+	switch scope := Scope(name); scope {
+	case ScopeRepo, ScopeGlobal, ScopeSystem:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidScope, name)
+	}
+}
+
+// flag returns the git config flag selecting the scope.
+func (s Scope) flag() string {
+	if s == ScopeRepo {
+		return "--local"
+	}
+
+	return "--" + string(s)
+}
+
+// Config returns the value of the config key in the scope, and whether it is
+// set. An empty scope reads the value git would use, from any scope.
+func (r Repo) Config(ctx context.Context, scope Scope, key string) (string, bool, error) {
+	args := []string{"config"}
+
+	if scope != "" {
+		args = append(args, scope.flag())
+	}
+
+	output, err := r.run(ctx, append(args, "--get", key)...)
+	if exitCode(err) == 1 {
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, err
+	}
+
+	return strings.TrimSuffix(string(output), "\n"), true, nil
+}
+
+// SetConfig sets the config key to the value in the scope.
+func (r Repo) SetConfig(ctx context.Context, scope Scope, key, value string) error {
+	_, err := r.run(ctx, "config", scope.flag(), key, value)
+
+	return err
+}
+
+// RemoveConfigSection removes the config section, such as "merge.go", from the
+// scope, reporting whether it was there to remove.
+func (r Repo) RemoveConfigSection(ctx context.Context, scope Scope, section string) (bool, error) {
+	_, err := r.run(ctx, "config", scope.flag(), "--remove-section", section)
+
+	// git exits with 128 when there is no such section.
+	if exitCode(err) == 128 && strings.Contains(err.Error(), "no such section") {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// TopLevel returns the top-level directory of the work tree.
+func (r Repo) TopLevel(ctx context.Context) (string, error) {
+	output, err := r.run(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+// exitCode returns the exit code of the failed git command, or -1 if the error
+// isn't from a git command exiting unsuccessfully.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return -1
+}
@@ -0,0 +1,70 @@
+package git_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScope(t *testing.T) {
+	t.Parallel()
+
+	scope, err := git.ParseScope("global")
+	require.NoError(t, err)
+
+	assert.Equal(t, git.ScopeGlobal, scope)
+
+	_, err = git.ParseScope("local")
+	require.ErrorIs(t, err, git.ErrInvalidScope)
+}
+
+func TestRepo_Config(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := initRepo(t)
+
+	_, ok, err := repo.Config(ctx, git.ScopeRepo, "merge.go.driver")
+	require.NoError(t, err)
+
+	assert.False(t, ok)
+
+	require.NoError(t, repo.SetConfig(ctx, git.ScopeRepo, "merge.go.name", "Go merge driver"))
+	require.NoError(t, repo.SetConfig(ctx, git.ScopeRepo, "merge.go.driver", "go-merge -O %O"))
+
+	driver, ok, err := repo.Config(ctx, git.ScopeRepo, "merge.go.driver")
+	require.NoError(t, err)
+
+	assert.True(t, ok)
+	assert.Equal(t, "go-merge -O %O", driver)
+
+	removed, err := repo.RemoveConfigSection(ctx, git.ScopeRepo, "merge.go")
+	require.NoError(t, err)
+
+	assert.True(t, removed)
+
+	_, ok, err = repo.Config(ctx, git.ScopeRepo, "merge.go.name")
+	require.NoError(t, err)
+
+	assert.False(t, ok)
+
+	// Removing the section again is a no-op.
+	removed, err = repo.RemoveConfigSection(ctx, git.ScopeRepo, "merge.go")
+	require.NoError(t, err)
+
+	assert.False(t, removed)
+}
+
+func TestRepo_TopLevel(t *testing.T) {
+	t.Parallel()
+
+	repo := initRepo(t)
+
+	topLevel, err := repo.TopLevel(context.Background())
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, topLevel)
+}
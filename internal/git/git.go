@@ -0,0 +1,186 @@
+// Package git runs the git commands the merge drivers need.
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Repo runs git commands in a git repository.
+type Repo struct {
+	// Dir is the directory git commands are run in. The current directory is
+	// used if it is empty.
+	Dir string
+}
+
+// ReadIndex reads the contents of the file at the path, relative to the top of
+// the work tree, from the stage of the index. Stage 0 holds merged files, and
+// stages 1, 2 and 3 hold the common ancestor, current and other versions of
+// unmerged files.
+func (r Repo) ReadIndex(ctx context.Context, stage int, path string) ([]byte, error) {
+	return r.run(ctx, "cat-file", "blob", fmt.Sprintf(":%d:%s", stage, path))
+}
+
+// UnmergedFile is a file with unmerged changes in the index.
+type UnmergedFile struct {
+	// Path is the path of the file, relative to the top of the work tree.
+	Path string
+
+	// Stages are the stages of the index holding a version of the file: 1 for
+	// the common ancestor, 2 for the current version and 3 for the other
+	// version. A version is missing if it doesn't have the file.
+	Stages []int
+}
+
+// UnmergedFiles returns the files with unmerged changes in the index, in the
+// order git lists them.
+func (r Repo) UnmergedFiles(ctx context.Context) ([]UnmergedFile, error) {
+	output, err := r.run(ctx, "ls-files", "-z", "--unmerged", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []UnmergedFile
+
+	// Each entry is the mode, object ID and stage, then a tab and the path.
+	for _, entry := range splitNul(output) {
+		info, path, ok := strings.Cut(entry, "\t")
+		fields := strings.Fields(info)
+
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("failed to parse unmerged file: %q", entry)
+		}
+
+		stage, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse unmerged file stage: %q: %w", entry, err)
+		}
+
+		if len(files) == 0 || files[len(files)-1].Path != path {
+			files = append(files, UnmergedFile{Path: path})
+		}
+
+		files[len(files)-1].Stages = append(files[len(files)-1].Stages, stage)
+	}
+
+	return files, nil
+}
+
+// Add adds the current contents of the files in the work tree to the index,
+// marking them as resolved if they were unmerged. Files deleted from the work
+// tree are removed from the index.
+func (r Repo) Add(ctx context.Context, paths ...string) error {
+	_, err := r.run(ctx, append([]string{"add", "--"}, paths...)...)
+
+	return err
+}
+
+// ReadCommit reads the contents of the file at the path, relative to the top
+// of the work tree, from the commit, or any other tree-ish.
+func (r Repo) ReadCommit(ctx context.Context, rev, path string) ([]byte, error) {
+	return r.run(ctx, "cat-file", "blob", rev+":"+path)
+}
+
+// CommitFiles returns the paths of the files in the commit, or any other
+// tree-ish, relative to the top of the work tree.
+func (r Repo) CommitFiles(ctx context.Context, rev string) ([]string, error) {
+	output, err := r.run(ctx, "ls-tree", "-r", "-z", "--name-only", "--full-tree", rev)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitNul(output), nil
+}
+
+// MergeBase returns the commit ID of the best common ancestor of the two
+// commits, as git merge would use.
+func (r Repo) MergeBase(ctx context.Context, this, other string) (string, error) {
+	output, err := r.run(ctx, "merge-base", this, other)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// run runs the git command, returning its output.
+func (r Repo) run(ctx context.Context, args ...string) ([]byte, error) {
+	return r.runInput(ctx, nil, args...)
+}
+
+// runInput runs the git command with the input on its standard input, returning
+// its output.
+func (r Repo) runInput(ctx context.Context, input io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.Dir
+	cmd.Stdin = input
+
+	var stderr bytes.Buffer
+
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to run git %s: %w: %s",
+			strings.Join(args, " "),
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	return output, nil
+}
+
+// Files returns the paths of the files in the work tree git knows of, tracked or
+// untracked but not ignored, relative to the directory.
+func (r Repo) Files(ctx context.Context) ([]string, error) {
+	output, err := r.run(ctx, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	return splitNul(output), nil
+}
+
+// CheckAttr returns the value of the attribute for each of the paths, as git
+// resolves it from every gitattributes file. Attributes which aren't set for a
+// path have the value "unspecified". The paths are passed on git's standard
+// input, so any number of them can be checked.
+func (r Repo) CheckAttr(ctx context.Context, attribute string, paths []string) (map[string]string, error) {
+	values := make(map[string]string, len(paths))
+
+	if len(paths) == 0 {
+		return values, nil
+	}
+
+	input := strings.Join(paths, "\x00") + "\x00"
+
+	output, err := r.runInput(ctx, strings.NewReader(input), "check-attr", "--stdin", "-z", attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each path is output as its path, attribute and value.
+	fields := splitNul(output)
+
+	for i := 0; i+2 < len(fields); i += 3 {
+		values[fields[i]] = fields[i+2]
+	}
+
+	return values, nil
+}
+
+// splitNul splits NUL-terminated git output into its fields.
+func splitNul(output []byte) []string {
+	if len(output) == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+}
@@ -0,0 +1,202 @@
+package git_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepo_ReadIndex(t *testing.T) {
+	t.Parallel()
+
+	repo := initRepo(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "go.mod"), []byte("module example.com/m\n"), 0o644))
+	runGit(t, repo, "add", "go.mod")
+
+	// Later changes to the work tree aren't in the index.
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "go.mod"), []byte("module example.com/changed\n"), 0o644))
+
+	contents, err := repo.ReadIndex(context.Background(), 0, "go.mod")
+	require.NoError(t, err)
+
+	assert.Equal(t, "module example.com/m\n", string(contents))
+}
+
+func TestRepo_ReadIndex_missing(t *testing.T) {
+	t.Parallel()
+
+	repo := initRepo(t)
+
+	_, err := repo.ReadIndex(context.Background(), 0, "go.mod")
+	require.Error(t, err)
+}
+
+func TestRepo_CheckAttr(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := initRepo(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repo.Dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, ".gitattributes"), []byte("go.mod merge=go\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "go.mod"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "go.sum"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "sub", "go.mod"), nil, 0o644))
+
+	files, err := repo.Files(ctx)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{".gitattributes", "go.mod", "go.sum", "sub/go.mod"}, files)
+
+	values, err := repo.CheckAttr(ctx, "merge", []string{"go.mod", "go.sum", "sub/go.mod"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"go.mod":     "go",
+		"go.sum":     "unspecified",
+		"sub/go.mod": "go",
+	}, values)
+}
+
+func TestRepo_CheckAttr_manyPaths(t *testing.T) {
+	t.Parallel()
+
+	repo := initRepo(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, ".gitattributes"), []byte("go.mod merge=go\n"), 0o644))
+
+	// More paths than fit on a command line, which needn't exist.
+	paths := make([]string, 0, 20000)
+
+	for i := range cap(paths) {
+		paths = append(paths, fmt.Sprintf("%s/%d/go.mod", strings.Repeat("module-directory-", 8), i))
+	}
+
+	values, err := repo.CheckAttr(context.Background(), "merge", paths)
+	require.NoError(t, err)
+
+	require.Len(t, values, len(paths))
+	assert.Equal(t, "go", values[paths[len(paths)-1]])
+}
+
+func TestRepo_MergeBase(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := initRepo(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "go.mod"), []byte("module example.com/m\n"), 0o644))
+	runGit(t, repo, "add", "go.mod")
+	runGit(t, repo, "commit", "--quiet", "-m", "base")
+	runGit(t, repo, "branch", "other")
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "go.sum"), nil, 0o644))
+	runGit(t, repo, "add", "go.sum")
+	runGit(t, repo, "commit", "--quiet", "-m", "current")
+
+	base, err := repo.MergeBase(ctx, "HEAD", "other")
+	require.NoError(t, err)
+
+	files, err := repo.CommitFiles(ctx, base)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"go.mod"}, files)
+
+	files, err = repo.CommitFiles(ctx, "HEAD")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"go.mod", "go.sum"}, files)
+
+	contents, err := repo.ReadCommit(ctx, base, "go.mod")
+	require.NoError(t, err)
+
+	assert.Equal(t, "module example.com/m\n", string(contents))
+
+	_, err = repo.ReadCommit(ctx, base, "go.sum")
+	require.Error(t, err)
+}
+
+func TestRepo_UnmergedFiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := initRepo(t)
+
+	writeFile := func(name, contents string) {
+		require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, name), []byte(contents), 0o644))
+	}
+
+	writeFile("go.mod", "module example.com/m\n")
+	writeFile("go.sum", "")
+	runGit(t, repo, "add", "go.mod", "go.sum")
+	runGit(t, repo, "commit", "--quiet", "-m", "base")
+	runGit(t, repo, "checkout", "--quiet", "-b", "other")
+
+	writeFile("go.mod", "module example.com/other\n")
+	runGit(t, repo, "rm", "--quiet", "go.sum")
+	runGit(t, repo, "commit", "--quiet", "-am", "other")
+	runGit(t, repo, "checkout", "--quiet", "-")
+
+	writeFile("go.mod", "module example.com/current\n")
+	writeFile("go.sum", "example.com/dep v1.0.0/go.mod h1:abc=\n")
+	runGit(t, repo, "commit", "--quiet", "-am", "current")
+
+	// The merge fails with conflicts, leaving the files unmerged.
+	cmd := exec.Command("git", "merge", "--quiet", "other")
+	cmd.Dir = repo.Dir
+	require.Error(t, cmd.Run())
+
+	files, err := repo.UnmergedFiles(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []git.UnmergedFile{
+		{Path: "go.mod", Stages: []int{1, 2, 3}},
+		{Path: "go.sum", Stages: []int{1, 2}},
+	}, files)
+
+	contents, err := repo.ReadIndex(ctx, 3, "go.mod")
+	require.NoError(t, err)
+
+	assert.Equal(t, "module example.com/other\n", string(contents))
+
+	writeFile("go.mod", "module example.com/merged\n")
+	require.NoError(t, repo.Add(ctx, "go.mod"))
+
+	files, err = repo.UnmergedFiles(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []git.UnmergedFile{{Path: "go.sum", Stages: []int{1, 2}}}, files)
+}
+
+// initRepo initialises an empty git repository in a temporary directory.
+func initRepo(t *testing.T) git.Repo {
+	t.Helper()
+
+	repo := git.Repo{Dir: t.TempDir()}
+
+	runGit(t, repo, "init", "--quiet")
+	runGit(t, repo, "config", "user.name", "Test")
+	runGit(t, repo, "config", "user.email", "test@example.com")
+
+	return repo
+}
+
+// runGit runs the git command in the repository, failing the test if it fails.
+func runGit(t *testing.T, repo git.Repo, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.Dir
+
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
@@ -0,0 +1,245 @@
+package gomod
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+	"golang.org/x/mod/modfile"
+)
+
+// conflictMarker is the comment used to find the statements of a conflict once
+// the merged file has been formatted.
+const conflictMarker = "// go-merge conflict "
+
+// Conflict describes a change to a go.mod or go.work file which could not be
+// merged automatically.
+type Conflict struct {
+	// Directive is the directive the conflict occurred in, such as "require"
+	// or "replace".
+	Directive string
+
+	// Path is the module path the conflicting statements refer to, or the key
+	// for godebug statements.
+	Path string
+
+	// Version is the module version the conflicting statements refer to, if
+	// they only apply to a single version of the module.
+	Version string
+
+	// Reason describes why the changes conflict.
+	Reason string
+
+	// Policy is the policy which reported the conflict, such as
+	// [PolicyProtected], if the changes could otherwise have been merged.
+	Policy string
+
+	// Current is the current side of the statement, without its directive.
+	// It is empty if the current side removed the statement.
+	Current string
+
+	// Other is the other side of the statement, without its directive. It is
+	// empty if the other side removed the statement.
+	Other string
+}
+
+// Ensure [Conflict] implements the [fmt.Stringer] interface.
+var _ fmt.Stringer = Conflict{}
+
+// String returns a human-readable description of the conflict.
+func (c Conflict) String() string {
+	path := c.Path
+
+	if c.Version != "" {
+		path += "@" + c.Version
+	}
+
+	if c.Policy != "" {
+		return fmt.Sprintf("%s %s: %s (%s policy)", c.Directive, path, c.Reason, c.Policy)
+	}
+
+	return fmt.Sprintf("%s %s: %s", c.Directive, path, c.Reason)
+}
+
+// swapped returns the conflict with the current and other sides swapped.
+func (c Conflict) swapped() Conflict {
+	c.Current, c.Other = c.Other, c.Current
+
+	return c
+}
+
+// FormatConflicts formats the merged go.mod file, surrounding the statements of
+// each conflict with git-style conflict markers. Conflicts whose statement is
+// not in the merged file are written at the end of the file.
+//
+// The syntax of the merged file is annotated in the process, so it should not
+// be formatted again afterwards.
+func FormatConflicts(merged modfile.File, conflicts []Conflict, labels markers.Labels) ([]byte, error) {
+	var (
+		trailing []int
+		lines    []*modfile.Line
+	)
+
+	// More than one conflict may refer to the same statement, so collect the
+	// conflicts of each line before marking it.
+	indices := make(map[*modfile.Line][]string)
+
+	for i, conflict := range conflicts {
+		line := conflictLine(merged, conflict)
+		if line == nil {
+			trailing = append(trailing, i)
+
+			continue
+		}
+
+		if _, ok := indices[line]; !ok {
+			lines = append(lines, line)
+		}
+
+		indices[line] = append(indices[line], strconv.Itoa(i))
+	}
+
+	for _, line := range lines {
+		marker := conflictMarker + strings.Join(indices[line], " ")
+
+		// Further suffix comments are written on lines of their own, so add
+		// the marker to any existing comment, such as "// indirect", to
+		// keep it on the statement's line.
+		if len(line.Suffix) > 0 {
+			line.Suffix[0].Token += " " + marker
+
+			continue
+		}
+
+		line.Suffix = append(line.Suffix, modfile.Comment{
+			Token:  marker,
+			Suffix: true,
+		})
+	}
+
+	formatted, err := merged.Format()
+	if err != nil {
+		return nil, fmt.Errorf("failed to format file: %w", err)
+	}
+
+	var b strings.Builder
+
+	for _, line := range strings.SplitAfter(string(formatted), "\n") {
+		_, marked, ok := strings.Cut(line, conflictMarker)
+		if !ok {
+			b.WriteString(line)
+
+			continue
+		}
+
+		for _, index := range strings.Fields(marked) {
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 || i >= len(conflicts) {
+				return nil, fmt.Errorf("failed to find conflict in file: %q", index)
+			}
+
+			// Statements within a block are indented, and written without
+			// their directive.
+			prefix := conflicts[i].Directive + " "
+
+			if strings.HasPrefix(line, "\t") {
+				prefix = "\t"
+			}
+
+			writeConflict(&b, conflicts[i], prefix, labels)
+		}
+	}
+
+	for _, i := range trailing {
+		b.WriteString("\n")
+		writeConflict(&b, conflicts[i], conflicts[i].Directive+" ", labels)
+	}
+
+	return []byte(b.String()), nil
+}
+
+// writeConflict writes the conflict block for the conflict, prefixing each
+// statement with the prefix.
+func writeConflict(b *strings.Builder, conflict Conflict, prefix string, labels markers.Labels) {
+	var current, other []string
+
+	if conflict.Current != "" {
+		current = append(current, prefix+conflict.Current)
+	}
+
+	if conflict.Other != "" {
+		other = append(other, prefix+conflict.Other)
+	}
+
+	for _, line := range markers.Block(current, other, labels) {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+}
+
+// conflictLine returns the line of the statement the conflict refers to in the
+// merged file, or nil if there is no such statement.
+func conflictLine(merged modfile.File, conflict Conflict) *modfile.Line {
+	switch conflict.Directive {
+	case "godebug":
+		for _, godebug := range merged.Godebug {
+			if godebug.Key == conflict.Path {
+				return godebug.Syntax
+			}
+		}
+	case "require":
+		for _, req := range merged.Require {
+			if req.Mod.Path == conflict.Path {
+				return req.Syntax
+			}
+		}
+	case "replace":
+		for _, rep := range merged.Replace {
+			if rep.Old.Path == conflict.Path && rep.Old.Version == conflict.Version {
+				return rep.Syntax
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatGodebug formats the godebug statement, without its directive.
+func formatGodebug(godebug modfile.Godebug) string {
+	return godebug.Key + "=" + godebug.Value
+}
+
+// formatRequire formats the require statement, without its directive.
+func formatRequire(req modfile.Require) string {
+	statement := modfile.AutoQuote(req.Mod.Path) + " " + req.Mod.Version
+
+	if req.Indirect {
+		statement += " // indirect"
+	}
+
+	return statement
+}
+
+// formatReplace formats the replace statement, without its directive.
+func formatReplace(rep modfile.Replace) string {
+	return strings.Join(replaceTokens(rep), " ")
+}
+
+// replaceTokens returns the tokens of the replace statement, without its
+// directive.
+func replaceTokens(rep modfile.Replace) []string {
+	tokens := []string{modfile.AutoQuote(rep.Old.Path)}
+
+	if rep.Old.Version != "" {
+		tokens = append(tokens, rep.Old.Version)
+	}
+
+	tokens = append(tokens, "=>", modfile.AutoQuote(rep.New.Path))
+
+	if rep.New.Version != "" {
+		tokens = append(tokens, rep.New.Version)
+	}
+
+	return tokens
+}
@@ -0,0 +1,176 @@
+package gomod_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatConflicts_replace(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require example.com/dep v1.0.0
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require example.com/dep v1.0.0
+
+replace example.com/dep => example.com/fork v1.0.0
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require example.com/dep v1.0.0
+
+replace example.com/dep => example.com/other-fork v1.0.0
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "replace", conflicts[0].Directive)
+
+	formatted, err := gomod.FormatConflicts(merged, conflicts, markers.Labels{
+		Current: "HEAD",
+		Other:   "feature",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `module example.com/m
+
+go 1.22
+
+require example.com/dep v1.0.0
+
+<<<<<<< HEAD
+replace example.com/dep => example.com/fork v1.0.0
+=======
+replace example.com/dep => example.com/other-fork v1.0.0
+>>>>>>> feature
+`, string(formatted))
+}
+
+func TestFormatConflicts_removedInBlock(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require (
+	example.com/dep v1.0.0
+	example.com/kept v1.0.0
+)
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require (
+	example.com/dep v1.1.0 // indirect
+	example.com/kept v1.0.0
+)
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require example.com/kept v1.0.0
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+
+	formatted, err := gomod.FormatConflicts(merged, conflicts, markers.DefaultLabels)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(formatted), `<<<<<<< current
+	example.com/dep v1.1.0 // indirect
+=======
+>>>>>>> other
+`)
+
+	// The statement is only written within the conflict block.
+	assert.Equal(t, 1, strings.Count(string(formatted), "example.com/dep v1.1.0"))
+}
+
+func TestFormatConflicts_sameLine(t *testing.T) {
+	t.Parallel()
+
+	merged := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require example.com/dep v1.1.0
+`)
+
+	conflicts := []gomod.Conflict{
+		{
+			Directive: "require",
+			Path:      "example.com/dep",
+			Reason:    "protected module changed",
+			Policy:    gomod.PolicyProtected,
+			Current:   "example.com/dep v1.1.0",
+			Other:     "example.com/dep v1.2.0",
+		},
+		{
+			Directive: "require",
+			Path:      "example.com/dep",
+			Reason:    "version v1.3.0 is denied",
+			Policy:    gomod.PolicyDenied,
+			Current:   "example.com/dep v1.1.0",
+			Other:     "example.com/dep v1.3.0",
+		},
+	}
+
+	formatted, err := gomod.FormatConflicts(*merged, conflicts, markers.DefaultLabels)
+	require.NoError(t, err)
+
+	// Both conflicts are written, so each can be found and resolved.
+	assert.Equal(t, `module example.com/m
+
+go 1.22
+
+<<<<<<< current
+require example.com/dep v1.1.0
+=======
+require example.com/dep v1.2.0
+>>>>>>> other
+<<<<<<< current
+require example.com/dep v1.1.0
+=======
+require example.com/dep v1.3.0
+>>>>>>> other
+`, string(formatted))
+}
+
+func TestFormatConflicts_none(t *testing.T) {
+	t.Parallel()
+
+	merged, err := gomod.Parse("testdata/expected.go.mod")
+	require.NoError(t, err)
+
+	formatted, err := gomod.FormatConflicts(*merged, nil, markers.DefaultLabels)
+	require.NoError(t, err)
+
+	expected, err := merged.Format()
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(formatted))
+}
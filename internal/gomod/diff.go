@@ -2,42 +2,50 @@ package gomod
 
 import (
 	"golang.org/x/mod/modfile"
-	"golang.org/x/mod/semver"
+	"golang.org/x/mod/module"
 )
 
 // Diff compares two modfile.File structs and returns the changes between them.
-// It checks for differences in the require, exclude, and replace statements.
+// It checks for differences in the toolchain, godebug, require, exclude, replace,
+// retract and tool statements.
 func Diff(version modfile.File, ancestor modfile.File) modfile.File {
-	ancestorStr, err := ancestor.Format()
-	if err != nil {
-		panic(err)
+	changes := modfile.File{
+		Module: ancestor.Module,
+		Go:     ancestor.Go,
 	}
 
-	changes, err := modfile.Parse(
-		ancestor.Syntax.Name,
-		[]byte(ancestorStr),
-		nil,
-	)
-	if err != nil {
-		panic(err)
-	}
-
-	semverVersionGoVersion := "v" + version.Go.Version
-	semverAncestorGoVersion := "v" + ancestor.Go.Version
-
-	semverComparison := semver.Compare(semverVersionGoVersion, semverAncestorGoVersion)
-
 	// If the new version uses a later Go version, then update the Go version.
-	if semverComparison > 0 {
+	if version.Go != nil && (ancestor.Go == nil || compareGoVersions(version.Go.Version, ancestor.Go.Version) > 0) {
 		changes.Go = version.Go
 	}
 
-	// Clear the require, exclude, replace and tool statements.
+	// Only record the toolchain if it changed.
+	if version.Toolchain != nil && (ancestor.Toolchain == nil || version.Toolchain.Name != ancestor.Toolchain.Name) {
+		changes.Toolchain = version.Toolchain
+	}
+
+	// Clear the godebug, require, exclude, replace, retract and tool
+	// statements.
+	changes.Godebug = []*modfile.Godebug{}
 	changes.Exclude = []*modfile.Exclude{}
 	changes.Replace = []*modfile.Replace{}
 	changes.Require = []*modfile.Require{}
+	changes.Retract = []*modfile.Retract{}
 	changes.Tool = []*modfile.Tool{}
 
+	ancestorGodebugs := make(map[string]string)
+
+	for _, godebug := range ancestor.Godebug {
+		ancestorGodebugs[godebug.Key] = godebug.Value
+	}
+
+	// Add the godebug statements.
+	for _, godebug := range version.Godebug {
+		if ancestorValue, ok := ancestorGodebugs[godebug.Key]; !ok || godebug.Value != ancestorValue {
+			changes.Godebug = append(changes.Godebug, godebug)
+		}
+	}
+
 	// Avoid quadratic behavior by creating a map of the ancestor require
 	// statements.
 	ancestorReqs := make(map[string]modfile.Require)
@@ -48,51 +56,165 @@ func Diff(version modfile.File, ancestor modfile.File) modfile.File {
 
 	// Add the require statements.
 	for _, req := range version.Require {
-		if ancestorReq, ok := ancestorReqs[req.Mod.Path]; !ok || *req != ancestorReq {
+		if ancestorReq, ok := ancestorReqs[req.Mod.Path]; !ok || !sameRequire(*req, ancestorReq) {
 			changes.Require = append(changes.Require, req)
 		}
 	}
 
-	ancestorExcludes := make(map[string]modfile.Exclude)
+	// A module may exclude several versions of the same module, so excludes
+	// are a set of module versions.
+	ancestorExcludes := make(map[module.Version]struct{})
 
 	for _, exc := range ancestor.Exclude {
-		ancestorExcludes[exc.Mod.Path] = *exc
+		ancestorExcludes[exc.Mod] = struct{}{}
 	}
 
-	// Add the exclude statements.
+	// Add the new exclude statements.
 	for _, exc := range version.Exclude {
-		if ancestorExc, ok := ancestorExcludes[exc.Mod.Path]; !ok || *exc != ancestorExc {
+		if _, ok := ancestorExcludes[exc.Mod]; !ok {
 			changes.Exclude = append(changes.Exclude, exc)
 		}
 	}
 
-	// Compute the existing ancestor replace statements.
-	ancestorReps := make(map[string]modfile.Replace)
+	// Replacements of a specific version are separate from replacements of
+	// every version, so the old path and version together identify them.
+	ancestorReps := make(map[module.Version]modfile.Replace)
 
 	for _, rep := range ancestor.Replace {
-		ancestorReps[rep.Old.Path] = *rep
+		ancestorReps[rep.Old] = *rep
 	}
 
 	// Add the modified replace statements.
 	for _, rep := range version.Replace {
-		if ancestorRep, ok := ancestorReps[rep.Old.Path]; !ok || *rep != ancestorRep {
+		if ancestorRep, ok := ancestorReps[rep.Old]; !ok || !sameReplace(*rep, ancestorRep) {
 			changes.Replace = append(changes.Replace, rep)
 		}
 	}
 
+	// Compute the existing ancestor retract statements.
+	ancestorRetracts := make(map[modfile.VersionInterval]string)
+
+	for _, retract := range ancestor.Retract {
+		ancestorRetracts[retract.VersionInterval] = retract.Rationale
+	}
+
+	// Add the new retract statements, or those with a changed rationale.
+	for _, retract := range version.Retract {
+		if rationale, ok := ancestorRetracts[retract.VersionInterval]; !ok || retract.Rationale != rationale {
+			changes.Retract = append(changes.Retract, retract)
+		}
+	}
+
 	// Compute the existing ancestor tool statements.
-	ancestorTools := make(map[string]modfile.Tool)
+	ancestorTools := make(map[string]struct{})
 
 	for _, tool := range ancestor.Tool {
-		ancestorTools[tool.Path] = *tool
+		ancestorTools[tool.Path] = struct{}{}
 	}
 
 	// Add the tool statements.
 	for _, tool := range version.Tool {
-		if ancestorTool, ok := ancestorTools[tool.Path]; !ok || *tool != ancestorTool {
+		if _, ok := ancestorTools[tool.Path]; !ok {
 			changes.Tool = append(changes.Tool, tool)
 		}
 	}
 
-	return *changes
+	return changes
+}
+
+// Removals compares two modfile.File structs and returns the statements of the
+// ancestor which are no longer present in the version. It checks the toolchain,
+// godebug, require, exclude, replace, retract and tool statements.
+func Removals(version modfile.File, ancestor modfile.File) modfile.File {
+	var removals modfile.File
+
+	if version.Toolchain == nil {
+		removals.Toolchain = ancestor.Toolchain
+	}
+
+	versionGodebugs := make(map[string]struct{})
+
+	for _, godebug := range version.Godebug {
+		versionGodebugs[godebug.Key] = struct{}{}
+	}
+
+	for _, godebug := range ancestor.Godebug {
+		if _, ok := versionGodebugs[godebug.Key]; !ok {
+			removals.Godebug = append(removals.Godebug, godebug)
+		}
+	}
+
+	versionReqs := make(map[string]struct{})
+
+	for _, req := range version.Require {
+		versionReqs[req.Mod.Path] = struct{}{}
+	}
+
+	for _, req := range ancestor.Require {
+		if _, ok := versionReqs[req.Mod.Path]; !ok {
+			removals.Require = append(removals.Require, req)
+		}
+	}
+
+	versionExcludes := make(map[module.Version]struct{})
+
+	for _, exc := range version.Exclude {
+		versionExcludes[exc.Mod] = struct{}{}
+	}
+
+	for _, exc := range ancestor.Exclude {
+		if _, ok := versionExcludes[exc.Mod]; !ok {
+			removals.Exclude = append(removals.Exclude, exc)
+		}
+	}
+
+	versionReps := make(map[module.Version]struct{})
+
+	for _, rep := range version.Replace {
+		versionReps[rep.Old] = struct{}{}
+	}
+
+	for _, rep := range ancestor.Replace {
+		if _, ok := versionReps[rep.Old]; !ok {
+			removals.Replace = append(removals.Replace, rep)
+		}
+	}
+
+	versionRetracts := make(map[modfile.VersionInterval]struct{})
+
+	for _, retract := range version.Retract {
+		versionRetracts[retract.VersionInterval] = struct{}{}
+	}
+
+	for _, retract := range ancestor.Retract {
+		if _, ok := versionRetracts[retract.VersionInterval]; !ok {
+			removals.Retract = append(removals.Retract, retract)
+		}
+	}
+
+	versionTools := make(map[string]struct{})
+
+	for _, tool := range version.Tool {
+		versionTools[tool.Path] = struct{}{}
+	}
+
+	for _, tool := range ancestor.Tool {
+		if _, ok := versionTools[tool.Path]; !ok {
+			removals.Tool = append(removals.Tool, tool)
+		}
+	}
+
+	return removals
+}
+
+// sameRequire reports whether two require statements require the same module
+// version, ignoring their syntax.
+func sameRequire(this, other modfile.Require) bool {
+	return this.Mod == other.Mod && this.Indirect == other.Indirect
+}
+
+// sameReplace reports whether two replace statements perform the same
+// replacement, ignoring their syntax.
+func sameReplace(this, other modfile.Replace) bool {
+	return this.Old == other.Old && this.New == other.New
 }
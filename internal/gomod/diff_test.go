@@ -66,3 +66,21 @@ func TestDiff_currentVsOther(t *testing.T) {
 
 	assert.True(t, replacesCobra)
 }
+
+func TestDiff_versionSpecificReplace(t *testing.T) {
+	t.Parallel()
+
+	current, err := gomod.Parse("testdata/replace_current.go.mod")
+	require.NoError(t, err)
+
+	ancestor, err := gomod.Parse("testdata/replace_ancestor.go.mod")
+	require.NoError(t, err)
+
+	diff := gomod.Diff(*current, *ancestor)
+
+	// The changed wildcard replace and the new version-specific replace are
+	// separate changes, while the unchanged v1.2.0 replace is not a change.
+	require.Len(t, diff.Replace, 2)
+	assert.Empty(t, diff.Replace[0].Old.Version)
+	assert.Equal(t, "v1.3.0", diff.Replace[1].Old.Version)
+}
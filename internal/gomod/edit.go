@@ -0,0 +1,290 @@
+package gomod
+
+import (
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// setRequire sets the requirement in the file, editing the existing require
+// statement in place if there is one. New requirements are added to the last
+// require block holding only direct or only indirect requirements to match.
+func setRequire(f *modfile.File, req modfile.Require) {
+	for _, r := range f.Require {
+		if r.Mod.Path == req.Mod.Path {
+			// Updates the first require statement for the path, which is r.
+			f.AddRequire(req.Mod.Path, req.Mod.Version)
+			setIndirect(r, req.Indirect)
+
+			return
+		}
+	}
+
+	line := insertLine(f, "require", []string{modfile.AutoQuote(req.Mod.Path), req.Mod.Version},
+		func(block *modfile.LineBlock) bool {
+			return len(block.Line) > 0 && !slices.ContainsFunc(block.Line, func(line *modfile.Line) bool {
+				return isIndirect(line) != req.Indirect
+			})
+		},
+	)
+
+	r := &modfile.Require{Mod: req.Mod, Syntax: line}
+	setIndirect(r, req.Indirect)
+
+	f.Require = append(f.Require, r)
+}
+
+// findRequire returns the require statement for the module at the path, or nil
+// if the file doesn't require it.
+func findRequire(f *modfile.File, path string) *modfile.Require {
+	for _, r := range f.Require {
+		if r.Mod.Path == path {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// setIndirect adds or removes the "// indirect" comment of the requirement,
+// keeping any other comment on the line.
+func setIndirect(r *modfile.Require, indirect bool) {
+	r.Indirect = indirect
+
+	line := r.Syntax
+	if isIndirect(line) == indirect {
+		return
+	}
+
+	if !indirect {
+		text := strings.TrimSpace(strings.TrimPrefix(line.Suffix[0].Token, "//"))
+		if text == "indirect" {
+			line.Suffix = nil
+
+			return
+		}
+
+		_, rest, _ := strings.Cut(line.Suffix[0].Token, "indirect;")
+		line.Suffix[0].Token = "//" + rest
+
+		return
+	}
+
+	if len(line.Suffix) == 0 {
+		line.Suffix = []modfile.Comment{{Token: "// indirect", Suffix: true}}
+
+		return
+	}
+
+	text := strings.TrimSpace(strings.TrimPrefix(line.Suffix[0].Token, "//"))
+	if text == "" {
+		line.Suffix[0].Token = "// indirect"
+
+		return
+	}
+
+	line.Suffix[0].Token = "// indirect; " + text
+}
+
+// isIndirect reports whether the line has an "// indirect" comment, in the same
+// way as the modfile package.
+func isIndirect(line *modfile.Line) bool {
+	if len(line.Suffix) == 0 {
+		return false
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(line.Suffix[0].Token, "//"))
+
+	return len(fields) == 1 && fields[0] == "indirect" || len(fields) > 1 && fields[0] == "indirect;"
+}
+
+// setReplace sets the replacement in the file, editing the replace statement
+// for the same old module version in place if there is one. Unlike
+// [modfile.File.AddReplace], replacing every version of a module leaves the
+// replacements of specific versions alone.
+func setReplace(f *modfile.File, rep modfile.Replace) {
+	tokens := replaceTokens(rep)
+
+	for _, r := range f.Replace {
+		if r.Old != rep.Old {
+			continue
+		}
+
+		r.New = rep.New
+
+		if r.Syntax.InBlock {
+			r.Syntax.Token = tokens
+		} else {
+			r.Syntax.Token = append([]string{"replace"}, tokens...)
+		}
+
+		return
+	}
+
+	line := insertLine(f, "replace", tokens, func(*modfile.LineBlock) bool {
+		return true
+	})
+
+	f.Replace = append(f.Replace, &modfile.Replace{Old: rep.Old, New: rep.New, Syntax: line})
+}
+
+// findReplace returns the replace statement for the old module version, or nil
+// if the file doesn't replace it.
+func findReplace(f *modfile.File, old module.Version) *modfile.Replace {
+	for _, r := range f.Replace {
+		if r.Old == old {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// setRetract sets the retraction of the version interval in the file, editing
+// the rationale of the existing retract statement for the interval in place if
+// there is one. Unlike [modfile.File.AddRetract], a new retraction never turns
+// a lone retract statement into a block.
+func setRetract(f *modfile.File, interval modfile.VersionInterval, rationale string) {
+	for _, r := range f.Retract {
+		if r.VersionInterval != interval {
+			continue
+		}
+
+		if r.Rationale != rationale {
+			r.Rationale = rationale
+			r.Syntax.Before = rationaleComments(rationale)
+			r.Syntax.Suffix = nil
+		}
+
+		return
+	}
+
+	tokens := []string{modfile.AutoQuote(interval.Low)}
+
+	if interval.Low != interval.High {
+		tokens = []string{"[", modfile.AutoQuote(interval.Low), ",", modfile.AutoQuote(interval.High), "]"}
+	}
+
+	line := insertLine(f, "retract", tokens, func(*modfile.LineBlock) bool {
+		return true
+	})
+	line.Before = rationaleComments(rationale)
+
+	f.Retract = append(f.Retract, &modfile.Retract{
+		VersionInterval: interval,
+		Rationale:       rationale,
+		Syntax:          line,
+	})
+}
+
+// rationaleComments returns the comments written before a retract statement
+// for the rationale, one per line.
+func rationaleComments(rationale string) []modfile.Comment {
+	if rationale == "" {
+		return nil
+	}
+
+	lines := strings.Split(rationale, "\n")
+	comments := make([]modfile.Comment, 0, len(lines))
+
+	for _, line := range lines {
+		comments = append(comments, modfile.Comment{Token: "// " + line})
+	}
+
+	return comments
+}
+
+// addExclude adds the exclude statement to the file, if it isn't already
+// present. Unlike [modfile.File.AddExclude], it never turns a lone exclude
+// statement into a block.
+func addExclude(f *modfile.File, mod module.Version) {
+	for _, exc := range f.Exclude {
+		if exc.Mod == mod {
+			return
+		}
+	}
+
+	line := insertLine(f, "exclude", []string{modfile.AutoQuote(mod.Path), mod.Version}, func(*modfile.LineBlock) bool {
+		return true
+	})
+
+	f.Exclude = append(f.Exclude, &modfile.Exclude{Mod: mod, Syntax: line})
+}
+
+// addTool adds the tool statement to the file, if it isn't already present.
+func addTool(f *modfile.File, path string) {
+	for _, tool := range f.Tool {
+		if tool.Path == path {
+			return
+		}
+	}
+
+	line := insertLine(f, "tool", []string{path}, func(*modfile.LineBlock) bool {
+		return true
+	})
+
+	f.Tool = append(f.Tool, &modfile.Tool{Path: path, Syntax: line})
+}
+
+// dropTool drops the tool statement from the file.
+func dropTool(f *modfile.File, path string) {
+	f.DropTool(path)
+
+	// Cleanup doesn't remove dropped tool statements, so do it here.
+	f.Tool = slices.DeleteFunc(f.Tool, func(tool *modfile.Tool) bool {
+		return tool.Path == ""
+	})
+}
+
+// insertLine adds a new line for the directive to the last block of that
+// directive accepted by the match function, before the first line which sorts
+// after it, so that sorted blocks stay sorted. Without such a block, the line
+// is added on its own after the last statement of that directive, or at the end
+// of the file.
+//
+// Unlike the modfile package, no other statement is moved or sorted.
+func insertLine(f *modfile.File, verb string, tokens []string, match func(*modfile.LineBlock) bool) *modfile.Line {
+	var block *modfile.LineBlock
+
+	last := len(f.Syntax.Stmt) - 1
+
+	for i, stmt := range f.Syntax.Stmt {
+		switch stmt := stmt.(type) {
+		case *modfile.Line:
+			if len(stmt.Token) > 0 && stmt.Token[0] == verb {
+				last = i
+			}
+		case *modfile.LineBlock:
+			if len(stmt.Token) > 0 && stmt.Token[0] == verb {
+				last = i
+
+				if match(stmt) {
+					block = stmt
+				}
+			}
+		}
+	}
+
+	if block != nil {
+		line := &modfile.Line{Token: tokens, InBlock: true}
+
+		i := slices.IndexFunc(block.Line, func(existing *modfile.Line) bool {
+			return len(existing.Token) > 0 && existing.Token[0] > tokens[0]
+		})
+		if i < 0 {
+			i = len(block.Line)
+		}
+
+		block.Line = slices.Insert(block.Line, i, line)
+
+		return line
+	}
+
+	line := &modfile.Line{Token: append([]string{verb}, tokens...)}
+
+	f.Syntax.Stmt = slices.Insert(f.Syntax.Stmt, last+1, modfile.Expr(line))
+
+	return line
+}
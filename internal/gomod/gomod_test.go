@@ -1,6 +1,22 @@
 package gomod_test
 
-import "golang.org/x/mod/modfile"
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+// parseModFile parses the given go.mod file contents.
+func parseModFile(t *testing.T, contents string) *modfile.File {
+	t.Helper()
+
+	mod, err := gomod.ParseData("go.mod", []byte(contents))
+	require.NoError(t, err)
+
+	return mod
+}
 
 // findRequires returns a slice of modfile.Require that match the given path.
 func findRequires(r modfile.File, path string) []*modfile.Require {
@@ -0,0 +1,241 @@
+package gomod
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// MajorVersion returns the path of the module without its major version
+// suffix, such as /v2, or .v2 for gopkg.in modules, and the module's major
+// version, such as v2. Modules without a suffix have the major version of their
+// version, so a module at v2.0.0+incompatible has major version v2.
+func MajorVersion(mod module.Version) (string, string) {
+	prefix, pathMajor, ok := module.SplitPathVersion(mod.Path)
+	if !ok {
+		return mod.Path, semver.Major(mod.Version)
+	}
+
+	if major := strings.TrimLeft(pathMajor, "/."); major != "" {
+		return prefix, major
+	}
+
+	return prefix, semver.Major(mod.Version)
+}
+
+// Migration is a move of a required module to another module path, with a
+// major version suffix, such as from github.com/x/y at v2.0.0+incompatible to
+// github.com/x/y/v2, or from gopkg.in/yaml.v2 to gopkg.in/yaml.v3.
+type Migration struct {
+	// From is the module version the ancestor required.
+	From module.Version
+
+	// To is the module version required instead.
+	To module.Version
+}
+
+// Ensure [Migration] implements the [fmt.Stringer] interface.
+var _ fmt.Stringer = Migration{}
+
+// String returns a human-readable description of the migration.
+func (m Migration) String() string {
+	return m.From.String() + " => " + m.To.String()
+}
+
+// Migrations returns the major version migrations of the go.mod file since the
+// common ancestor: modules whose requirement it removed, while adding a
+// requirement of the same module at a higher major version, or at the same
+// major version if the removed requirement was of an +incompatible version. A
+// module migrated to several major versions at once is migrated to the
+// highest.
+func Migrations(version, ancestor modfile.File) []Migration {
+	var migrations []Migration
+
+	for _, from := range ancestor.Require {
+		if findRequire(&version, from.Mod.Path) != nil {
+			continue
+		}
+
+		prefix, fromMajor := MajorVersion(from.Mod)
+		incompatible := strings.HasSuffix(from.Mod.Version, "+incompatible")
+
+		var (
+			to      module.Version
+			toMajor string
+		)
+
+		for _, req := range version.Require {
+			if findRequire(&ancestor, req.Mod.Path) != nil {
+				continue
+			}
+
+			reqPrefix, reqMajor := MajorVersion(req.Mod)
+
+			c := semver.Compare(reqMajor, fromMajor)
+
+			if reqPrefix == prefix && (c > 0 || c == 0 && incompatible) &&
+				(toMajor == "" || semver.Compare(reqMajor, toMajor) > 0) {
+				to, toMajor = req.Mod, reqMajor
+			}
+		}
+
+		if toMajor != "" {
+			migrations = append(migrations, Migration{From: from.Mod, To: to})
+		}
+	}
+
+	return migrations
+}
+
+// mergeMigrations collapses the requirements of modules migrated to another
+// major version path on one side, and still required at the old path by the
+// other, into the migrated requirement, so the merged file doesn't require the
+// module at both paths. Changes to the old requirement made by the side which
+// didn't migrate are dropped, so the conflicts between them and the migrating
+// side's removal of the old requirement are replaced by the returned conflicts.
+//
+// Migrations to different module paths on each side, and to a lower major
+// version than the other side requires at the old path, are reported as
+// conflicts. Pinned and protected modules are left to their policies.
+func mergeMigrations(
+	merged *modfile.File,
+	current, other, ancestor modfile.File,
+	conflicts []Conflict,
+	opts Options,
+) []Conflict {
+	currentMigrations := Migrations(current, ancestor)
+	otherMigrations := Migrations(other, ancestor)
+
+	for _, migration := range currentMigrations {
+		if opts.IsPinned(migration.From.Path) || opts.IsProtected(migration.From.Path) ||
+			opts.IsPinned(migration.To.Path) || opts.IsProtected(migration.To.Path) {
+			continue
+		}
+
+		i := slices.IndexFunc(otherMigrations, func(m Migration) bool {
+			return m.From.Path == migration.From.Path
+		})
+
+		if i >= 0 {
+			conflicts = mergeBothMigrated(merged, migration, otherMigrations[i], conflicts, opts)
+
+			continue
+		}
+
+		stale := findRequire(&other, migration.From.Path)
+
+		conflicts = collapseMigration(merged, migration, stale, conflicts, opts, "current")
+	}
+
+	for _, migration := range otherMigrations {
+		if opts.IsPinned(migration.From.Path) || opts.IsProtected(migration.From.Path) ||
+			opts.IsPinned(migration.To.Path) || opts.IsProtected(migration.To.Path) {
+			continue
+		}
+
+		if slices.ContainsFunc(currentMigrations, func(m Migration) bool {
+			return m.From.Path == migration.From.Path
+		}) {
+			continue
+		}
+
+		stale := findRequire(&current, migration.From.Path)
+
+		conflicts = collapseMigration(merged, migration, stale, conflicts, opts, "other")
+	}
+
+	return conflicts
+}
+
+// mergeBothMigrated merges a module migrated on both sides, which only
+// conflicts if the sides migrated it to different module paths. The current
+// side's migration is kept.
+func mergeBothMigrated(merged *modfile.File, current, other Migration, conflicts []Conflict, opts Options) []Conflict {
+	if current.To.Path == other.To.Path {
+		return conflicts
+	}
+
+	opts.Log().Warn(
+		"module migrated to different major versions on each side",
+		slog.String("module", current.From.Path),
+		slog.String("current", current.To.String()),
+		slog.String("other", other.To.String()),
+	)
+
+	conflict := Conflict{
+		Directive: "require",
+		Path:      current.To.Path,
+		Reason:    fmt.Sprintf("%s migrated to different major versions on each side", current.From.Path),
+		Current:   formatRequire(modfile.Require{Mod: current.To}),
+		Other:     formatRequire(modfile.Require{Mod: other.To}),
+	}
+
+	if currentReq := findRequire(merged, current.To.Path); currentReq != nil {
+		conflict.Current = formatRequire(*currentReq)
+	}
+
+	if otherReq := findRequire(merged, other.To.Path); otherReq != nil {
+		conflict.Other = formatRequire(*otherReq)
+
+		merged.DropRequire(other.To.Path)
+	}
+
+	return append(conflicts, conflict)
+}
+
+// collapseMigration collapses the stale requirement of the module at its old
+// path, as required by the side which didn't migrate it, into the migrated
+// requirement, if the merged file still requires it. side names the side which
+// migrated it.
+func collapseMigration(
+	merged *modfile.File,
+	migration Migration,
+	stale *modfile.Require,
+	conflicts []Conflict,
+	opts Options,
+	side string,
+) []Conflict {
+	if stale == nil || findRequire(merged, migration.From.Path) == nil {
+		return conflicts
+	}
+
+	_, staleMajor := MajorVersion(stale.Mod)
+	_, toMajor := MajorVersion(migration.To)
+
+	// The side which didn't migrate moved to an even higher major version at
+	// the old path, so the conflict between its change and the migrating
+	// side's removal stands.
+	if semver.Compare(staleMajor, toMajor) > 0 {
+		opts.Log().Warn(
+			"not collapsing requirement changed to a higher major version than its migration",
+			slog.String("module", stale.Mod.String()),
+			slog.String("migrated", migration.To.String()),
+		)
+
+		return conflicts
+	}
+
+	opts.Log().Info(
+		"collapsed requirement into its migrated module",
+		slog.String("module", stale.Mod.String()),
+		slog.String("migrated", migration.To.String()),
+		slog.String("reason", fmt.Sprintf("%s side migrated %s to %s", side, migration.From.Path, migration.To.Path)),
+	)
+
+	merged.DropRequire(migration.From.Path)
+
+	return removeConflicts(conflicts, migration.From.Path)
+}
+
+// removeConflicts returns the conflicts without those of require statements
+// for the module at the path.
+func removeConflicts(conflicts []Conflict, path string) []Conflict {
+	return slices.DeleteFunc(conflicts, func(c Conflict) bool {
+		return c.Directive == "require" && c.Path == path
+	})
+}
@@ -0,0 +1,187 @@
+package gomod_test
+
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+func TestMajorVersion(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		mod    module.Version
+		prefix string
+		major  string
+	}{
+		{module.Version{Path: "github.com/x/y", Version: "v2.0.0+incompatible"}, "github.com/x/y", "v2"},
+		{module.Version{Path: "github.com/x/y/v2", Version: "v2.1.0"}, "github.com/x/y", "v2"},
+		{module.Version{Path: "github.com/x/y", Version: "v1.4.0"}, "github.com/x/y", "v1"},
+		{module.Version{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"}, "gopkg.in/yaml", "v3"},
+	} {
+		t.Run(test.mod.String(), func(t *testing.T) {
+			t.Parallel()
+
+			prefix, major := gomod.MajorVersion(test.mod)
+
+			assert.Equal(t, test.prefix, prefix)
+			assert.Equal(t, test.major, major)
+		})
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require (
+	github.com/x/y v2.0.0+incompatible
+	gopkg.in/yaml.v2 v2.4.0
+	example.com/kept v1.0.0
+)
+`)
+
+	version := parseModFile(t, `module example.com/m
+
+require (
+	github.com/x/y/v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
+	example.com/kept/v2 v2.0.0
+	example.com/kept v1.0.0
+)
+`)
+
+	// Modules still required at the old path aren't migrated.
+	assert.Equal(t, []gomod.Migration{
+		{
+			From: module.Version{Path: "github.com/x/y", Version: "v2.0.0+incompatible"},
+			To:   module.Version{Path: "github.com/x/y/v2", Version: "v2.1.0"},
+		},
+		{
+			From: module.Version{Path: "gopkg.in/yaml.v2", Version: "v2.4.0"},
+			To:   module.Version{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"},
+		},
+	}, gomod.Migrations(*version, *ancestor))
+}
+
+func TestMerge_migration(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require (
+	github.com/x/y v2.0.0+incompatible
+	gopkg.in/yaml.v2 v2.4.0
+)
+`)
+
+	bumped := parseModFile(t, `module example.com/m
+
+require (
+	github.com/x/y v2.0.1+incompatible
+	gopkg.in/yaml.v2 v2.4.1
+)
+`)
+
+	migrated := parseModFile(t, `module example.com/m
+
+require (
+	github.com/x/y/v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+`)
+
+	for name, sides := range map[string][2]int{
+		"other migrated":   {0, 1},
+		"current migrated": {1, 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			versions := [2]*modfile.File{bumped, migrated}
+
+			merged, conflicts, err := gomod.Merge(*versions[sides[0]], *versions[sides[1]], *ancestor)
+			require.NoError(t, err)
+			require.Empty(t, conflicts)
+
+			// The bumped requirements at the old paths are collapsed into the
+			// migrated requirements.
+			assert.Empty(t, findRequires(merged, "github.com/x/y"))
+			assert.Empty(t, findRequires(merged, "gopkg.in/yaml.v2"))
+
+			requires := findRequires(merged, "github.com/x/y/v2")
+			require.Len(t, requires, 1)
+
+			assert.Equal(t, "v2.1.0", requires[0].Mod.Version)
+			assert.Len(t, findRequires(merged, "gopkg.in/yaml.v3"), 1)
+		})
+	}
+}
+
+func TestMerge_migrationDivergent(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require github.com/x/y v2.0.0+incompatible
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require github.com/x/y/v2 v2.1.0
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require github.com/x/y/v3 v3.0.0
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+
+	assert.Equal(t, []gomod.Conflict{
+		{
+			Directive: "require",
+			Path:      "github.com/x/y/v2",
+			Reason:    "github.com/x/y migrated to different major versions on each side",
+			Current:   "github.com/x/y/v2 v2.1.0",
+			Other:     "github.com/x/y/v3 v3.0.0",
+		},
+	}, conflicts)
+
+	assert.Len(t, findRequires(merged, "github.com/x/y/v2"), 1)
+	assert.Empty(t, findRequires(merged, "github.com/x/y/v3"))
+}
+
+func TestMerge_migrationToLowerMajor(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require github.com/x/y v2.0.0+incompatible
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require github.com/x/y v3.0.0+incompatible
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require github.com/x/y/v2 v2.1.0
+`)
+
+	_, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+
+	// Collapsing would downgrade the current side, so the removal conflicts.
+	require.Len(t, conflicts, 1)
+
+	assert.Equal(t, "github.com/x/y", conflicts[0].Path)
+	assert.Equal(t, "removed on one side but changed on the other", conflicts[0].Reason)
+}
@@ -1,105 +1,541 @@
 package gomod
 
 import (
-	"strings"
+	"cmp"
+	"fmt"
+	"log/slog"
 
 	"golang.org/x/mod/modfile"
-	"golang.org/x/mod/semver"
+	"golang.org/x/mod/module"
 )
 
-// Merge merges the changes between the current and other go.mod files into the
-// common ancestor go.mod file.
-func Merge(current, other, ancestor modfile.File) modfile.File {
-	currentChanges := Diff(current, ancestor)
-	otherChanges := Diff(other, ancestor)
+// changeset holds the statements one side of a merge changed and removed,
+// relative to the common ancestor.
+type changeset struct {
+	changes  modfile.File
+	removals modfile.File
+}
 
-	mergedChanges := mergeChanges(currentChanges, otherChanges)
+// newChangeset computes the changeset of the version relative to the ancestor.
+func newChangeset(version, ancestor modfile.File) changeset {
+	return changeset{
+		changes:  Diff(version, ancestor),
+		removals: Removals(version, ancestor),
+	}
+}
 
-	// Now merge back into the ancestor file.
-	return mergeChanges(mergedChanges, ancestor)
+// Merge merges the changes between the common ancestor and other go.mod files
+// into the current go.mod file.
+//
+// The current file is edited in place, so its comments, blocks and ordering are
+// kept for every statement the other side didn't change.
+//
+// Statements removed on one side and left untouched on the other are removed
+// from the result. Statements removed on one side but changed on the other
+// keep the changed statement, and are reported as conflicts. Replacements
+// changed to different modules or local directories on each side keep the
+// current replacement, and are reported as conflicts, as are godebug settings
+// changed to different values on each side.
+//
+// Retractions are merged as a set, combining overlapping version intervals.
+//
+// The toolchain is merged like the go version, picking the highest toolchain
+// set on either side, and is dropped if the merged go version makes it
+// redundant.
+func Merge(current, other, ancestor modfile.File) (modfile.File, []Conflict, error) {
+	return MergeWithOptions(current, other, ancestor, Options{})
 }
 
-// mergeChanges merges the two changesets, preferring higher-versioned values,
-// then the current changes over the other changes.
-func mergeChanges(currentChanges, otherChanges modfile.File) modfile.File {
-	otherReqs := make(map[string]modfile.Require)
+// MergeWithOptions merges the go.mod files like [Merge], configured by the
+// options. It returns an error if the current file has no syntax to edit, such
+// as one built by hand rather than parsed, or the merged file can't be parsed
+// again.
+func MergeWithOptions(current, other, ancestor modfile.File, opts Options) (modfile.File, []Conflict, error) {
+	merged, err := clone(current)
+	if err != nil {
+		return modfile.File{}, nil, err
+	}
+
+	conflicts := MergeInto(merged, current, other, ancestor, opts)
 
-	for _, req := range otherChanges.Require {
-		otherReqs[req.Mod.Path] = *req
+	merged.Cleanup()
+
+	// Not every edit updates the parsed statements, so parse the merged file
+	// again.
+	reparsed, err := clone(*merged)
+	if err != nil {
+		return modfile.File{}, nil, err
 	}
 
-	for _, req := range currentChanges.Require {
-		otherReq, ok := otherReqs[req.Mod.Path]
-		if !ok {
-			continue
+	return *reparsed, conflicts, nil
+}
+
+// MergeInto merges the changes between the common ancestor and other files into
+// the merged file, which must start as a copy of the current file, following
+// the rules of [Merge] and the options. The merged file is edited in place and is not cleaned
+// up, so that files sharing the syntax of go.mod files, such as go.work files,
+// can be merged through a [modfile.File] holding their shared statements.
+func MergeInto(merged *modfile.File, current, other, ancestor modfile.File, opts Options) []Conflict {
+	currentChangeset := newChangeset(current, ancestor)
+	otherChangeset := newChangeset(other, ancestor)
+
+	mergeGo(merged, currentChangeset, otherChangeset)
+
+	var conflicts []Conflict
+
+	conflicts = append(conflicts, mergeGodebugs(merged, currentChangeset, otherChangeset)...)
+	conflicts = append(conflicts, protectedRequires(current, other, currentChangeset, otherChangeset, opts)...)
+	conflicts = append(conflicts, mergeRequires(merged, ancestor, currentChangeset, otherChangeset, opts)...)
+	conflicts = append(conflicts, protectedReplaces(current, other, currentChangeset, otherChangeset, opts)...)
+	conflicts = append(conflicts, mergeReplaces(merged, currentChangeset, otherChangeset, opts)...)
+
+	conflicts = mergeMigrations(merged, current, other, ancestor, conflicts, opts)
+
+	mergeExcludes(merged, otherChangeset)
+	mergeTools(merged, otherChangeset)
+	mergeRetracts(merged, ancestor, currentChangeset, otherChangeset)
+
+	return conflicts
+}
+
+// mergeGo merges the go and toolchain statements, picking the highest version
+// of each. A toolchain statement made redundant by the merge is dropped.
+func mergeGo(merged *modfile.File, current, other changeset) {
+	goBefore, toolchainBefore := goStmts(merged)
+
+	// Pick the highest version of Go required.
+	if other.changes.Go != nil && other.changes.Go.Version != "" &&
+		(merged.Go == nil || merged.Go.Version == "" || compareGoVersions(other.changes.Go.Version, merged.Go.Version) > 0) {
+		merged.AddGoStmt(other.changes.Go.Version)
+	}
+
+	switch {
+	case other.changes.Toolchain != nil:
+		// Pick the highest toolchain required, if both sides changed it.
+		toolchain := other.changes.Toolchain
+
+		if current.changes.Toolchain != nil {
+			toolchain = maxToolchain(current.changes.Toolchain, toolchain)
 		}
 
-		// If the other require statement is a higher version, then update the
-		// current require statement.
-		if semver.Compare(req.Mod.Version, otherReq.Mod.Version) < 0 {
-			req.Mod.Version = otherReq.Mod.Version
+		merged.AddToolchainStmt(toolchain.Name)
+	case other.removals.Toolchain != nil && current.changes.Toolchain == nil:
+		merged.DropToolchainStmt()
+	}
+
+	// Leave the current side's statements as they were unless the merge
+	// changed them.
+	if goAfter, toolchainAfter := goStmts(merged); goAfter != goBefore || toolchainAfter != toolchainBefore {
+		dropRedundantToolchain(merged)
+	}
+}
+
+// goStmts returns the go version and toolchain name of the file, which are
+// empty if it has no such statement.
+func goStmts(f *modfile.File) (string, string) {
+	var goVersion, toolchain string
+
+	if f.Go != nil {
+		goVersion = f.Go.Version
+	}
+
+	if f.Toolchain != nil {
+		toolchain = f.Toolchain.Name
+	}
+
+	return goVersion, toolchain
+}
+
+// mergeGodebugs merges the godebug statements key by key.
+func mergeGodebugs(merged *modfile.File, current, other changeset) []Conflict {
+	var conflicts []Conflict
+
+	currentChanged := make(map[string]modfile.Godebug)
+
+	for _, godebug := range current.changes.Godebug {
+		currentChanged[godebug.Key] = *godebug
+	}
+
+	currentRemoved := make(map[string]struct{})
+
+	for _, godebug := range current.removals.Godebug {
+		currentRemoved[godebug.Key] = struct{}{}
+	}
+
+	for _, godebug := range other.changes.Godebug {
+		if _, ok := currentRemoved[godebug.Key]; ok {
+			conflicts = append(conflicts, Conflict{
+				Directive: "godebug",
+				Path:      godebug.Key,
+				Reason:    "removed on one side but changed on the other",
+				Other:     formatGodebug(*godebug),
+			})
+		} else if currentGodebug, ok := currentChanged[godebug.Key]; ok {
+			if currentGodebug.Value != godebug.Value {
+				conflicts = append(conflicts, Conflict{
+					Directive: "godebug",
+					Path:      godebug.Key,
+					Reason:    "set to different values on each side",
+					Current:   formatGodebug(currentGodebug),
+					Other:     formatGodebug(*godebug),
+				})
+			}
+
+			// Godebug settings can't be ordered, so the current setting is
+			// kept.
+			continue
 		}
 
-		if !otherReq.Indirect {
-			req.Indirect = false
+		merged.AddGodebug(godebug.Key, godebug.Value)
+	}
+
+	for _, godebug := range other.removals.Godebug {
+		if currentGodebug, ok := currentChanged[godebug.Key]; ok {
+			conflicts = append(conflicts, Conflict{
+				Directive: "godebug",
+				Path:      godebug.Key,
+				Reason:    "removed on one side but changed on the other",
+				Current:   formatGodebug(currentGodebug),
+			})
+
+			continue
 		}
 
-		req.Syntax.InBlock = false
+		merged.DropGodebug(godebug.Key)
 	}
 
-	otherChanges.SetRequireSeparateIndirect(currentChanges.Require)
+	return conflicts
+}
+
+// mergeRequires merges the require statements, selecting the version with the
+// module's strategy when both sides changed a requirement.
+func mergeRequires(merged *modfile.File, ancestor modfile.File, current, other changeset, opts Options) []Conflict {
+	var conflicts []Conflict
 
-	for _, exc := range currentChanges.Exclude {
-		otherChanges.AddExclude(exc.Mod.Path, exc.Mod.Version)
+	ancestorVersions := make(map[string]string)
+
+	for _, req := range ancestor.Require {
+		ancestorVersions[req.Mod.Path] = req.Mod.Version
 	}
 
-	otherReps := make(map[string]modfile.Replace)
+	currentChanged := make(map[string]modfile.Require)
 
-	for _, rep := range otherChanges.Replace {
-		otherReps[rep.Old.Path] = *rep
+	for _, req := range current.changes.Require {
+		currentChanged[req.Mod.Path] = *req
 	}
 
-	for _, rep := range currentChanges.Replace {
-		// Update the replace statement if the current replace is a higher
-		// version than the existing one.
-		if otherRep, ok := otherReps[rep.Old.Path]; !ok || semver.Compare(rep.New.Version, otherRep.New.Version) > 0 {
-			otherChanges.AddReplace(rep.Old.Path, rep.Old.Version, rep.New.Path, rep.New.Version)
-		}
+	currentRemoved := make(map[string]struct{})
+
+	for _, req := range current.removals.Require {
+		currentRemoved[req.Mod.Path] = struct{}{}
 	}
 
-	// Compute the existing tool statements.
-	otherTools := make(map[string]struct{})
+	for _, req := range other.changes.Require {
+		resolved := *req
+
+		// Protected modules are never merged.
+		if opts.IsProtected(req.Mod.Path) {
+			continue
+		}
+
+		if opts.IsPinned(req.Mod.Path) {
+			opts.Log().Info(
+				"ignoring other side's change to pinned module",
+				slog.String("directive", "require"),
+				slog.String("module", req.Mod.Path),
+				slog.String("version", req.Mod.Version),
+			)
+
+			continue
+		}
+
+		if _, ok := currentRemoved[req.Mod.Path]; ok {
+			conflicts = append(conflicts, Conflict{
+				Directive: "require",
+				Path:      req.Mod.Path,
+				Reason:    "removed on one side but changed on the other",
+				Other:     formatRequire(*req),
+			})
+		} else if currentReq, ok := currentChanged[req.Mod.Path]; ok {
+			resolved = currentReq
+
+			version, reason, err := opts.SelectVersion(
+				req.Mod.Path,
+				currentReq.Mod.Version,
+				req.Mod.Version,
+				ancestorVersions[req.Mod.Path],
+			)
+			if err != nil {
+				conflicts = append(conflicts, Conflict{
+					Directive: "require",
+					Path:      req.Mod.Path,
+					Reason:    err.Error(),
+					Current:   formatRequire(currentReq),
+					Other:     formatRequire(*req),
+				})
+
+				continue
+			}
+
+			opts.Log().Info(
+				"selected version of requirement changed on both sides",
+				slog.String("module", req.Mod.Path),
+				slog.String("current", currentReq.Mod.Version),
+				slog.String("other", req.Mod.Version),
+				slog.String("selected", version),
+				slog.String("reason", reason),
+			)
+
+			resolved.Mod.Version = version
+
+			if !req.Indirect {
+				resolved.Indirect = false
+			}
+		} else {
+			opts.Log().Debug(
+				"took requirement changed only on the other side",
+				slog.String("module", req.Mod.Path),
+				slog.String("version", req.Mod.Version),
+			)
+		}
+
+		if opts.IsDenied(resolved.Mod) {
+			conflict := Conflict{
+				Directive: "require",
+				Path:      req.Mod.Path,
+				Reason:    fmt.Sprintf("version %s is denied", resolved.Mod.Version),
+				Policy:    PolicyDenied,
+				Other:     formatRequire(*req),
+			}
+
+			if currentReq := findRequire(merged, req.Mod.Path); currentReq != nil {
+				conflict.Current = formatRequire(*currentReq)
+			}
 
-	for _, tool := range otherChanges.Tool {
-		otherTools[tool.Path] = struct{}{}
+			conflicts = append(conflicts, conflict)
+
+			continue
+		}
+
+		setRequire(merged, resolved)
 	}
 
-	// Only add non-duplicate tool statements.
-	for _, tool := range currentChanges.Tool {
-		// Update the tool statement if the current tool is a higher version
-		// than the existing one.
-		if _, ok := otherTools[tool.Path]; !ok {
-			otherChanges.AddTool(tool.Path)
+	for _, req := range other.removals.Require {
+		if opts.IsProtected(req.Mod.Path) {
+			continue
+		}
+
+		if opts.IsPinned(req.Mod.Path) {
+			opts.Log().Info(
+				"ignoring other side's removal of pinned module",
+				slog.String("directive", "require"),
+				slog.String("module", req.Mod.Path),
+			)
+
+			continue
 		}
+
+		if currentReq, ok := currentChanged[req.Mod.Path]; ok {
+			conflicts = append(conflicts, Conflict{
+				Directive: "require",
+				Path:      req.Mod.Path,
+				Reason:    "removed on one side but changed on the other",
+				Current:   formatRequire(currentReq),
+			})
+
+			continue
+		}
+
+		merged.DropRequire(req.Mod.Path)
 	}
 
-	goVersions := make([]string, 0, 2)
+	return conflicts
+}
 
-	if currentChanges.Go != nil && currentChanges.Go.Version != "" {
-		goVersions = append(goVersions, "v"+currentChanges.Go.Version)
+// mergeExcludes merges the exclude statements as a set of module versions.
+// Excluded versions can only be added or removed, so they never conflict.
+func mergeExcludes(merged *modfile.File, other changeset) {
+	for _, exc := range other.changes.Exclude {
+		addExclude(merged, exc.Mod)
 	}
 
-	if otherChanges.Go != nil && otherChanges.Go.Version != "" {
-		goVersions = append(goVersions, "v"+otherChanges.Go.Version)
+	for _, exc := range other.removals.Exclude {
+		merged.DropExclude(exc.Mod.Path, exc.Mod.Version)
 	}
+}
 
-	semver.Sort(goVersions)
+// mergeReplaces merges the replace statements, preferring the higher version
+// when both sides replaced a module with the same module.
+//
+// Replacements of a specific module version and of every version of a module
+// are merged separately, as the go command applies the version-specific
+// replacement to that version in preference to the other.
+func mergeReplaces(merged *modfile.File, current, other changeset, opts Options) []Conflict {
+	var conflicts []Conflict
 
-	// Pick the highest version of Go required.
-	maxGoVersion := strings.TrimPrefix(goVersions[len(goVersions)-1], "v")
-	otherChanges.AddGoStmt(maxGoVersion)
+	currentChanged := make(map[module.Version]modfile.Replace)
+
+	for _, rep := range current.changes.Replace {
+		currentChanged[rep.Old] = *rep
+	}
+
+	currentRemoved := make(map[module.Version]struct{})
+
+	for _, rep := range current.removals.Replace {
+		currentRemoved[rep.Old] = struct{}{}
+	}
+
+	for _, rep := range other.changes.Replace {
+		// Protected modules are never merged.
+		if opts.IsProtected(rep.Old.Path) {
+			continue
+		}
+
+		if opts.IsPinned(rep.Old.Path) {
+			opts.Log().Info(
+				"ignoring other side's change to pinned module",
+				slog.String("directive", "replace"),
+				slog.String("module", rep.Old.Path),
+				slog.String("replacement", formatReplace(*rep)),
+			)
+
+			continue
+		}
+
+		if _, ok := currentRemoved[rep.Old]; ok {
+			conflicts = append(conflicts, Conflict{
+				Directive: "replace",
+				Path:      rep.Old.Path,
+				Version:   rep.Old.Version,
+				Reason:    "removed on one side but changed on the other",
+				Other:     formatReplace(*rep),
+			})
+		} else if currentRep, ok := currentChanged[rep.Old]; ok {
+			if currentRep.New == rep.New {
+				continue
+			}
+
+			// Replacements with local directories have no version to pick
+			// between, so can only be merged when both sides agree.
+			if reason := replaceConflictReason(currentRep, *rep); reason != "" {
+				conflicts = append(conflicts, Conflict{
+					Directive: "replace",
+					Path:      rep.Old.Path,
+					Version:   rep.Old.Version,
+					Reason:    reason,
+					Current:   formatReplace(currentRep),
+					Other:     formatReplace(*rep),
+				})
+
+				continue
+			}
+
+			// Keep the current replace statement unless the other replace
+			// is a higher version.
+			c, reason := cmp.Or(opts.Pseudo, PseudoSemver).Compare(rep.New.Version, currentRep.New.Version)
+
+			selected := currentRep.New.Version
 
-	otherChanges.Cleanup()
+			if c > 0 {
+				selected = rep.New.Version
+			}
 
-	return otherChanges
+			opts.Log().Info(
+				"selected replacement version changed on both sides",
+				slog.String("module", rep.Old.Path),
+				slog.String("current", currentRep.New.Version),
+				slog.String("other", rep.New.Version),
+				slog.String("selected", selected),
+				slog.String("reason", "higher version, by "+reason),
+			)
+
+			if c <= 0 {
+				continue
+			}
+		}
+
+		if rep.New.Version != "" && opts.IsDenied(rep.New) {
+			conflict := Conflict{
+				Directive: "replace",
+				Path:      rep.Old.Path,
+				Version:   rep.Old.Version,
+				Reason:    fmt.Sprintf("replacement version %s is denied", rep.New.Version),
+				Policy:    PolicyDenied,
+				Other:     formatReplace(*rep),
+			}
+
+			if currentRep := findReplace(merged, rep.Old); currentRep != nil {
+				conflict.Current = formatReplace(*currentRep)
+			}
+
+			conflicts = append(conflicts, conflict)
+
+			continue
+		}
+
+		setReplace(merged, *rep)
+	}
+
+	for _, rep := range other.removals.Replace {
+		if opts.IsProtected(rep.Old.Path) {
+			continue
+		}
+
+		if opts.IsPinned(rep.Old.Path) {
+			opts.Log().Info(
+				"ignoring other side's removal of pinned module",
+				slog.String("directive", "replace"),
+				slog.String("module", rep.Old.Path),
+			)
+
+			continue
+		}
+
+		if currentRep, ok := currentChanged[rep.Old]; ok {
+			conflicts = append(conflicts, Conflict{
+				Directive: "replace",
+				Path:      rep.Old.Path,
+				Version:   rep.Old.Version,
+				Reason:    "removed on one side but changed on the other",
+				Current:   formatReplace(currentRep),
+			})
+
+			continue
+		}
+
+		merged.DropReplace(rep.Old.Path, rep.Old.Version)
+	}
+
+	return conflicts
+}
+
+// replaceConflictReason returns why two different replacements of the same
+// module conflict, or an empty string if they only differ in version and so can
+// be merged by picking the higher version.
+func replaceConflictReason(current, other modfile.Replace) string {
+	currentDir := modfile.IsDirectoryPath(current.New.Path)
+	otherDir := modfile.IsDirectoryPath(other.New.Path)
+
+	switch {
+	case currentDir && otherDir:
+		return "replaced with different directories on each side"
+	case currentDir || otherDir:
+		return "replaced with a directory on one side and a module on the other"
+	case current.New.Path != other.New.Path:
+		return "replaced with different modules on each side"
+	default:
+		return ""
+	}
+}
+
+// mergeTools merges the tool statements. Tools can only be added or removed,
+// so they never conflict.
+func mergeTools(merged *modfile.File, other changeset) {
+	for _, tool := range other.changes.Tool {
+		addTool(merged, tool.Path)
+	}
+
+	for _, tool := range other.removals.Tool {
+		dropTool(merged, tool.Path)
+	}
 }
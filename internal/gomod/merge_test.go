@@ -7,6 +7,7 @@ import (
 	"github.com/crystalix007/go-merge-drivers/internal/gomod"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
 )
 
 func TestMerge(t *testing.T) {
@@ -21,7 +22,9 @@ func TestMerge(t *testing.T) {
 	ancestor, err := gomod.Parse("testdata/ancestor.go.mod")
 	require.NoError(t, err)
 
-	merged := gomod.Merge(*current, *other, *ancestor)
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
 
 	// Check the merged go.mod file Go version.
 	assert.Equal(t, "1.24.0", merged.Go.Version)
@@ -66,3 +69,706 @@ func TestMerge(t *testing.T) {
 
 	assert.Equal(t, string(expected), string(actual))
 }
+
+func TestMerge_removed(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require (
+	example.com/kept v1.0.0
+	example.com/removed v1.0.0
+)
+
+exclude example.com/removed v1.1.0
+
+replace example.com/removed => example.com/fork v1.0.0
+
+tool example.com/removed/cmd
+`)
+
+	// The current side tidied away the removed module.
+	current := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require example.com/kept v1.0.0
+`)
+
+	// The other side bumped an unrelated module.
+	other := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require (
+	example.com/kept v1.1.0
+	example.com/removed v1.0.0
+)
+
+exclude example.com/removed v1.1.0
+
+replace example.com/removed => example.com/fork v1.0.0
+
+tool example.com/removed/cmd
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	keptRequires := findRequires(merged, "example.com/kept")
+	require.Len(t, keptRequires, 1)
+	assert.Equal(t, "v1.1.0", keptRequires[0].Mod.Version)
+
+	assert.Empty(t, findRequires(merged, "example.com/removed"))
+	assert.Empty(t, findReplaces(merged, "example.com/removed"))
+	assert.Empty(t, merged.Exclude)
+	assert.Empty(t, merged.Tool)
+}
+
+func TestMerge_removedAndChanged(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require example.com/dep v1.0.0
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+go 1.22
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require example.com/dep v1.2.0
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "require", conflicts[0].Directive)
+	assert.Equal(t, "example.com/dep", conflicts[0].Path)
+	assert.Empty(t, conflicts[0].Current)
+	assert.Equal(t, "example.com/dep v1.2.0", conflicts[0].Other)
+
+	// The changed requirement is kept, for the conflict to be resolved.
+	depRequires := findRequires(merged, "example.com/dep")
+	require.Len(t, depRequires, 1)
+	assert.Equal(t, "v1.2.0", depRequires[0].Mod.Version)
+}
+
+func TestMerge_noSyntax(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, "module example.com/m\n")
+	other := parseModFile(t, "module example.com/m\n\ngo 1.22\n")
+
+	_, _, err := gomod.Merge(modfile.File{}, *other, *ancestor)
+	require.ErrorIs(t, err, gomod.ErrNoSyntax)
+}
+
+func TestMerge_toolchain(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		ancestor, current, other string
+		expected                 string
+	}{
+		"highest": {
+			ancestor: "go 1.22.0\n",
+			current:  "go 1.22.0\ntoolchain go1.22.4\n",
+			other:    "go 1.22.0\ntoolchain go1.22.6\n",
+			expected: "go1.22.6",
+		},
+		"one side": {
+			ancestor: "go 1.22.0\ntoolchain go1.22.4\n",
+			current:  "go 1.22.0\ntoolchain go1.22.4\n",
+			other:    "go 1.22.0\ntoolchain go1.22.2\n",
+			expected: "go1.22.2",
+		},
+		"removed": {
+			ancestor: "go 1.22.0\ntoolchain go1.22.4\n",
+			current:  "go 1.22.0\n",
+			other:    "go 1.22.0\ntoolchain go1.22.4\n",
+			expected: "",
+		},
+		"redundant with go version": {
+			ancestor: "go 1.22.0\n",
+			current:  "go 1.22.0\ntoolchain go1.22.4\n",
+			other:    "go 1.23.0\n",
+			expected: "",
+		},
+		"equal to go version": {
+			ancestor: "go 1.22.0\n",
+			current:  "go 1.22.0\ntoolchain go1.23.0\n",
+			other:    "go 1.23.0\n",
+			expected: "",
+		},
+		"equal to go version untouched by the merge": {
+			ancestor: "go 1.22.0\n",
+			current:  "go 1.23.0\ntoolchain go1.23.0\n",
+			other:    "go 1.22.0\n",
+			expected: "go1.23.0",
+		},
+		"default": {
+			ancestor: "go 1.22.0\ntoolchain go1.22.4\n",
+			current:  "go 1.22.0\ntoolchain default\n",
+			other:    "go 1.22.0\ntoolchain go1.22.4\n",
+			expected: "default",
+		},
+		"named beats default": {
+			ancestor: "go 1.22.0\n",
+			current:  "go 1.22.0\ntoolchain default\n",
+			other:    "go 1.22.0\ntoolchain go1.22.1\n",
+			expected: "go1.22.1",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ancestor := parseModFile(t, "module example.com/m\n\n"+test.ancestor)
+			current := parseModFile(t, "module example.com/m\n\n"+test.current)
+			other := parseModFile(t, "module example.com/m\n\n"+test.other)
+
+			merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+			require.NoError(t, err)
+			require.Empty(t, conflicts)
+
+			if test.expected == "" {
+				assert.Nil(t, merged.Toolchain)
+
+				return
+			}
+
+			require.NotNil(t, merged.Toolchain)
+			assert.Equal(t, test.expected, merged.Toolchain.Name)
+		})
+	}
+}
+
+func TestMerge_goVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		current, other string
+		expected       string
+	}{
+		"highest": {
+			current:  "1.21.5",
+			other:    "1.22.0",
+			expected: "1.22.0",
+		},
+		"current prerelease": {
+			current:  "1.22rc1",
+			other:    "1.21.5",
+			expected: "1.22rc1",
+		},
+		"other prerelease": {
+			current:  "1.21.5",
+			other:    "1.22rc1",
+			expected: "1.22rc1",
+		},
+		"release beats prerelease": {
+			current:  "1.22rc1",
+			other:    "1.22.0",
+			expected: "1.22.0",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ancestor := parseModFile(t, "module example.com/m\n\ngo 1.21.0\n")
+			current := parseModFile(t, "module example.com/m\n\ngo "+test.current+"\n")
+			other := parseModFile(t, "module example.com/m\n\ngo "+test.other+"\n")
+
+			merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+			require.NoError(t, err)
+			require.Empty(t, conflicts)
+
+			require.NotNil(t, merged.Go)
+			assert.Equal(t, test.expected, merged.Go.Version)
+		})
+	}
+}
+
+func TestMerge_godebug(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+go 1.23
+
+godebug (
+	default=go1.21
+	panicnil=1
+	tlsrsakex=1
+	x509sha1=1
+)
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+go 1.23
+
+godebug (
+	default=go1.21
+	panicnil=1
+	tlsrsakex=0
+	x509sha1=1
+)
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+go 1.23
+
+godebug (
+	default=go1.21
+	httpmuxgo121=1
+	tlsrsakex=1
+	x509sha1=1
+)
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	godebugs := make(map[string]string)
+
+	for _, godebug := range merged.Godebug {
+		godebugs[godebug.Key] = godebug.Value
+	}
+
+	assert.Equal(t, map[string]string{
+		"default":      "go1.21",
+		"httpmuxgo121": "1",
+		"tlsrsakex":    "0",
+		"x509sha1":     "1",
+	}, godebugs)
+}
+
+func TestMerge_godebugConflict(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, "module example.com/m\n\ngo 1.23\n\ngodebug panicnil=1\n")
+	current := parseModFile(t, "module example.com/m\n\ngo 1.23\n\ngodebug panicnil=0\n")
+	other := parseModFile(t, "module example.com/m\n\ngo 1.23\n\ngodebug panicnil=2\n")
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, gomod.Conflict{
+		Directive: "godebug",
+		Path:      "panicnil",
+		Reason:    "set to different values on each side",
+		Current:   "panicnil=0",
+		Other:     "panicnil=2",
+	}, conflicts[0])
+
+	// The current setting is kept, for the conflict to be resolved.
+	require.Len(t, merged.Godebug, 1)
+	assert.Equal(t, "0", merged.Godebug[0].Value)
+}
+
+func TestMerge_retract(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+go 1.22
+
+// Published accidentally.
+retract v0.1.0
+
+// Broken build.
+retract v1.0.0
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+go 1.22
+
+// Published accidentally.
+retract v0.1.0
+
+// Broken build.
+retract v1.0.0
+
+// Data race in the client.
+retract [v1.1.0, v1.1.2]
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+go 1.22
+
+// Broken build.
+retract v1.0.0
+
+// Panics on startup.
+retract v1.1.3
+
+// Leaks credentials.
+retract v1.2.0
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	actual, err := merged.Format()
+	require.NoError(t, err)
+
+	assert.Equal(t, `module example.com/m
+
+go 1.22
+
+// Broken build.
+retract v1.0.0
+
+// Data race in the client.
+retract [v1.1.0, v1.1.2]
+
+// Panics on startup.
+retract v1.1.3
+
+// Leaks credentials.
+retract v1.2.0
+`, string(actual))
+}
+
+func TestMerge_retractUntouched(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, "module example.com/m\n\ngo 1.22\n\n// Published accidentally.\nretract v0.1.0\n")
+	current := parseModFile(t, "module example.com/m\n\ngo 1.22\n\n// Published accidentally.\nretract v0.1.0\n")
+	other := parseModFile(t, `module example.com/m
+
+go 1.22
+
+retract (
+	// Published accidentally.
+	v0.1.0
+	// Broken build.
+	v1.0.0
+)
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	actual, err := merged.Format()
+	require.NoError(t, err)
+
+	// The current side's retraction is left as it was.
+	assert.Equal(t, `module example.com/m
+
+go 1.22
+
+// Published accidentally.
+retract v0.1.0
+
+// Broken build.
+retract v1.0.0
+`, string(actual))
+}
+
+func TestMerge_retractOverlapping(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, "module example.com/m\n\ngo 1.22\n")
+	current := parseModFile(t, "module example.com/m\n\ngo 1.22\n\n// Data race.\nretract [v1.1.0, v1.1.2]\n")
+	other := parseModFile(t, "module example.com/m\n\ngo 1.22\n\n// Panics.\nretract [v1.1.2, v1.1.4]\n")
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	// Intervals sharing an endpoint are combined.
+	require.Len(t, merged.Retract, 1)
+	assert.Equal(t, modfile.VersionInterval{Low: "v1.1.0", High: "v1.1.4"}, merged.Retract[0].VersionInterval)
+	assert.Equal(t, "Data race.\nPanics.", merged.Retract[0].Rationale)
+}
+
+func TestMerge_retractSameInterval(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, "module example.com/m\n\ngo 1.22\n")
+	current := parseModFile(t, "module example.com/m\n\ngo 1.22\n\n// Broken build.\nretract v1.0.0\n")
+	other := parseModFile(t, "module example.com/m\n\ngo 1.22\n\n// Missing files.\nretract v1.0.0\n")
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	require.Len(t, merged.Retract, 1)
+	assert.Equal(t, "v1.0.0", merged.Retract[0].Low)
+	assert.Equal(t, "Broken build.\nMissing files.", merged.Retract[0].Rationale)
+}
+
+func TestMerge_preservesFormatting(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.0.0
+	example.com/c v1.0.0 // indirect
+)
+`)
+
+	current := parseModFile(t, `// Module m does things.
+module example.com/m
+
+go 1.22
+
+require (
+	// Pinned until the API migration lands.
+	example.com/b v1.0.0
+
+	example.com/a v1.0.0 // keep in sync with b
+)
+
+// Indirect dependencies.
+require example.com/c v1.0.0 // indirect
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+go 1.22
+
+require (
+	example.com/a v1.1.0
+	example.com/b v1.0.0
+	example.com/c v1.0.0 // indirect
+	example.com/d v1.0.0
+)
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	actual, err := merged.Format()
+	require.NoError(t, err)
+
+	assert.Equal(t, `// Module m does things.
+module example.com/m
+
+go 1.22
+
+require (
+	// Pinned until the API migration lands.
+	example.com/b v1.0.0
+
+	example.com/a v1.1.0 // keep in sync with b
+	example.com/d v1.0.0
+)
+
+// Indirect dependencies.
+require example.com/c v1.0.0 // indirect
+`, string(actual))
+}
+
+func TestMerge_versionSpecificReplaces(t *testing.T) {
+	t.Parallel()
+
+	current, err := gomod.Parse("testdata/replace_current.go.mod")
+	require.NoError(t, err)
+
+	other, err := gomod.Parse("testdata/replace_other.go.mod")
+	require.NoError(t, err)
+
+	ancestor, err := gomod.Parse("testdata/replace_ancestor.go.mod")
+	require.NoError(t, err)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	depReplaces := findReplaces(merged, "example.com/dep")
+	require.Len(t, depReplaces, 2)
+
+	// Use the current wildcard replacement, as it is greater.
+	assert.Empty(t, depReplaces[0].Old.Version)
+	assert.Equal(t, "v1.1.0", depReplaces[0].New.Version)
+
+	// Keep the version-specific replacement added by current, and drop the
+	// one removed by other.
+	assert.Equal(t, "v1.3.0", depReplaces[1].Old.Version)
+	assert.Equal(t, "../dep", depReplaces[1].New.Path)
+
+	actual, err := merged.Format()
+	require.NoError(t, err)
+
+	expected, err := os.ReadFile("testdata/replace_expected.go.mod")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestMerge_excludes(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+go 1.22
+
+exclude (
+	example.com/dep v1.1.0
+	example.com/dep v1.2.0
+)
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+go 1.22
+
+exclude (
+	example.com/dep v1.1.0
+	example.com/dep v1.2.0
+	example.com/dep v1.3.0
+)
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+go 1.22
+
+exclude (
+	example.com/dep v1.2.0
+	example.com/dep v1.2.1
+)
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	excluded := make([]string, 0, len(merged.Exclude))
+
+	for _, exc := range merged.Exclude {
+		excluded = append(excluded, exc.Mod.String())
+	}
+
+	assert.ElementsMatch(t, []string{
+		"example.com/dep@v1.2.0",
+		"example.com/dep@v1.2.1",
+		"example.com/dep@v1.3.0",
+	}, excluded)
+}
+
+func TestMerge_excludeUntouched(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, "module example.com/m\n\ngo 1.22\n\nexclude example.com/dep v1.1.0\n")
+	current := parseModFile(t, "module example.com/m\n\ngo 1.22\n\nexclude example.com/dep v1.1.0 // broken\n")
+	other := parseModFile(t, `module example.com/m
+
+go 1.22
+
+exclude (
+	example.com/dep v1.1.0
+	example.com/dep v1.2.0
+)
+`)
+
+	merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	actual, err := merged.Format()
+	require.NoError(t, err)
+
+	// The current side's exclusion is left as it was.
+	assert.Equal(t, `module example.com/m
+
+go 1.22
+
+exclude example.com/dep v1.1.0 // broken
+
+exclude example.com/dep v1.2.0
+`, string(actual))
+}
+
+func TestMerge_directoryReplaces(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		current, other string
+		expected       string
+		conflict       string
+	}{
+		"agree": {
+			current:  "replace example.com/dep => ../forks/dep\n",
+			other:    "replace example.com/dep => ../forks/dep\n",
+			expected: "../forks/dep",
+		},
+		"current changed": {
+			current:  "replace example.com/dep => ../forks/dep\n",
+			other:    "replace example.com/dep => ../dep\n",
+			expected: "../forks/dep",
+		},
+		"other changed": {
+			current:  "replace example.com/dep => ../dep\n",
+			other:    "replace example.com/dep => ./vendor/dep\n",
+			expected: "./vendor/dep",
+		},
+		"diverged": {
+			current:  "replace example.com/dep => ../forks/dep\n",
+			other:    "replace example.com/dep => ./third_party/dep\n",
+			expected: "../forks/dep",
+			conflict: "replaced with different directories on each side",
+		},
+		"directory and module": {
+			current:  "replace example.com/dep => ../forks/dep\n",
+			other:    "replace example.com/dep => example.com/fork v1.0.0\n",
+			expected: "../forks/dep",
+			conflict: "replaced with a directory on one side and a module on the other",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			const header = "module example.com/m\n\ngo 1.22\n\n"
+
+			ancestor := parseModFile(t, header+"replace example.com/dep => ../dep\n")
+			current := parseModFile(t, header+test.current)
+			other := parseModFile(t, header+test.other)
+
+			merged, conflicts, err := gomod.Merge(*current, *other, *ancestor)
+			require.NoError(t, err)
+
+			if test.conflict == "" {
+				require.Empty(t, conflicts)
+			} else {
+				require.Len(t, conflicts, 1)
+				assert.Equal(t, test.conflict, conflicts[0].Reason)
+			}
+
+			depReplaces := findReplaces(merged, "example.com/dep")
+			require.Len(t, depReplaces, 1)
+			assert.Equal(t, test.expected, depReplaces[0].New.Path)
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package gomod
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"golang.org/x/mod/modfile"
 )
 
+// ErrNoSyntax is returned when merging a go.mod file which wasn't parsed, and so
+// has no syntax to edit.
+var ErrNoSyntax = errors.New("gomod: go.mod file has no syntax")
+
 // Parse parses the contents of a go.mod file and returns a modfile.File
 // object.
 func Parse(filename string) (*modfile.File, error) {
@@ -19,6 +24,12 @@ func Parse(filename string) (*modfile.File, error) {
 		)
 	}
 
+	return ParseData(filename, data)
+}
+
+// ParseData parses the contents of a go.mod file already read, such as from the
+// git index, and returns a modfile.File object.
+func ParseData(filename string, data []byte) (*modfile.File, error) {
 	mod, err := modfile.Parse(filename, data, nil)
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -32,3 +43,32 @@ func Parse(filename string) (*modfile.File, error) {
 
 	return mod, nil
 }
+
+// clone returns a deep copy of the go.mod file, by formatting and parsing it
+// again. This also brings the parsed statements up to date with any edits made
+// to the syntax.
+func clone(f modfile.File) (*modfile.File, error) {
+	if f.Syntax == nil {
+		return nil, ErrNoSyntax
+	}
+
+	data, err := f.Format()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to format go.mod file (%s): %w",
+			f.Syntax.Name,
+			err,
+		)
+	}
+
+	cloned, err := modfile.Parse(f.Syntax.Name, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to parse formatted go.mod file (%s): %w",
+			f.Syntax.Name,
+			err,
+		)
+	}
+
+	return cloned, nil
+}
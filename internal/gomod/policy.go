@@ -0,0 +1,162 @@
+package gomod
+
+import (
+	"log/slog"
+	"slices"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// The policies which report conflicts for changes which could otherwise be
+// merged.
+const (
+	// PolicyProtected reports changes to protected modules.
+	PolicyProtected = "protected"
+
+	// PolicyDenied reports changes selecting denied module versions.
+	PolicyDenied = "denied"
+)
+
+// IsPinned reports whether the module at the path is pinned.
+func (o Options) IsPinned(path string) bool {
+	return matchAny(o.Pinned, path)
+}
+
+// IsProtected reports whether the module at the path is protected.
+func (o Options) IsProtected(path string) bool {
+	return matchAny(o.Protected, path)
+}
+
+// IsDenied reports whether the module version is denied.
+func (o Options) IsDenied(mod module.Version) bool {
+	return slices.Contains(o.Denied, mod)
+}
+
+// Log returns the logger to log the merge's choices to, which discards them if
+// Logger is nil.
+func (o Options) Log() *slog.Logger {
+	if o.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+
+	return o.Logger
+}
+
+// matchAny reports whether any of the patterns match the module path.
+func matchAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if module.MatchPrefixPatterns(pattern, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// protectedRequires reports a conflict for each protected module whose
+// requirement either side changed or removed, unless both sides require the
+// same version. The current requirement is kept.
+func protectedRequires(current, other modfile.File, currentChanges, otherChanges changeset, opts Options) []Conflict {
+	var (
+		conflicts []Conflict
+		seen      = make(map[string]bool)
+	)
+
+	for _, changes := range [][]*modfile.Require{
+		currentChanges.changes.Require,
+		currentChanges.removals.Require,
+		otherChanges.changes.Require,
+		otherChanges.removals.Require,
+	} {
+		for _, req := range changes {
+			path := req.Mod.Path
+
+			if seen[path] || !opts.IsProtected(path) {
+				continue
+			}
+
+			seen[path] = true
+
+			currentReq := findRequire(&current, path)
+			otherReq := findRequire(&other, path)
+
+			if currentReq != nil && otherReq != nil && currentReq.Mod == otherReq.Mod ||
+				currentReq == nil && otherReq == nil {
+				continue
+			}
+
+			conflict := Conflict{
+				Directive: "require",
+				Path:      path,
+				Reason:    "protected module changed",
+				Policy:    PolicyProtected,
+			}
+
+			if currentReq != nil {
+				conflict.Current = formatRequire(*currentReq)
+			}
+
+			if otherReq != nil {
+				conflict.Other = formatRequire(*otherReq)
+			}
+
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	return conflicts
+}
+
+// protectedReplaces reports a conflict for each replacement of a protected
+// module which either side changed or removed, unless both sides have the same
+// replacement. The current replacement is kept.
+func protectedReplaces(current, other modfile.File, currentChanges, otherChanges changeset, opts Options) []Conflict {
+	var (
+		conflicts []Conflict
+		seen      = make(map[module.Version]bool)
+	)
+
+	for _, changes := range [][]*modfile.Replace{
+		currentChanges.changes.Replace,
+		currentChanges.removals.Replace,
+		otherChanges.changes.Replace,
+		otherChanges.removals.Replace,
+	} {
+		for _, rep := range changes {
+			if seen[rep.Old] || !opts.IsProtected(rep.Old.Path) {
+				continue
+			}
+
+			seen[rep.Old] = true
+
+			currentRep := findReplace(&current, rep.Old)
+			otherRep := findReplace(&other, rep.Old)
+
+			if currentRep != nil && otherRep != nil && currentRep.New == otherRep.New ||
+				currentRep == nil && otherRep == nil {
+				continue
+			}
+
+			conflict := Conflict{
+				Directive: "replace",
+				Path:      rep.Old.Path,
+				Version:   rep.Old.Version,
+				Reason:    "protected module changed",
+				Policy:    PolicyProtected,
+			}
+
+			if currentRep != nil {
+				conflict.Current = formatReplace(*currentRep)
+			}
+
+			if otherRep != nil {
+				conflict.Other = formatReplace(*otherRep)
+			}
+
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	return conflicts
+}
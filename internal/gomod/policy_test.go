@@ -0,0 +1,252 @@
+package gomod_test
+
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+)
+
+func TestMergeWithOptions_pinned(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require (
+	example.com/platform/sdk v1.1.0
+	example.com/platform/old v1.0.0
+	example.com/dep v1.1.0
+)
+
+replace example.com/platform/sdk => example.com/fork/sdk v1.1.0
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require (
+	example.com/platform/sdk v1.1.0
+	example.com/platform/old v1.0.0
+	example.com/dep v1.1.0
+)
+
+replace example.com/platform/sdk => example.com/fork/sdk v1.1.0
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require (
+	example.com/platform/sdk v1.2.0
+	example.com/dep v1.2.0
+)
+
+replace example.com/platform/sdk => example.com/fork/sdk v1.2.0
+`)
+
+	merged, conflicts, err := gomod.MergeWithOptions(*current, *other, *ancestor, gomod.Options{
+		Pinned: []string{"example.com/platform"},
+	})
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	// The other side's changes to pinned modules are ignored.
+	sdk := findRequires(merged, "example.com/platform/sdk")
+	require.Len(t, sdk, 1)
+
+	assert.Equal(t, "v1.1.0", sdk[0].Mod.Version)
+
+	assert.Len(t, findRequires(merged, "example.com/platform/old"), 1)
+
+	replaces := findReplaces(merged, "example.com/platform/sdk")
+	require.Len(t, replaces, 1)
+
+	assert.Equal(t, "v1.1.0", replaces[0].New.Version)
+
+	// Other modules are merged as usual.
+	dep := findRequires(merged, "example.com/dep")
+	require.Len(t, dep, 1)
+
+	assert.Equal(t, "v1.2.0", dep[0].Mod.Version)
+}
+
+func TestMergeWithOptions_denied(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require (
+	example.com/dep v1.1.0
+	example.com/both v1.1.0
+)
+
+replace example.com/forked => example.com/fork v1.0.0
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require (
+	example.com/dep v1.1.0
+	example.com/both v1.3.0
+)
+
+replace example.com/forked => example.com/fork v1.0.0
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require (
+	example.com/dep v1.2.0
+	example.com/both v1.2.0
+	example.com/added v1.0.0
+)
+
+replace example.com/forked => example.com/fork v1.1.0
+`)
+
+	merged, conflicts, err := gomod.MergeWithOptions(*current, *other, *ancestor, gomod.Options{
+		Denied: []module.Version{
+			{Path: "example.com/dep", Version: "v1.2.0"},
+			{Path: "example.com/both", Version: "v1.3.0"},
+			{Path: "example.com/added", Version: "v1.0.0"},
+			{Path: "example.com/fork", Version: "v1.1.0"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []gomod.Conflict{
+		{
+			Directive: "require",
+			Path:      "example.com/dep",
+			Reason:    "version v1.2.0 is denied",
+			Policy:    gomod.PolicyDenied,
+			Current:   "example.com/dep v1.1.0",
+			Other:     "example.com/dep v1.2.0",
+		},
+		{
+			Directive: "require",
+			Path:      "example.com/both",
+			Reason:    "version v1.3.0 is denied",
+			Policy:    gomod.PolicyDenied,
+			Current:   "example.com/both v1.3.0",
+			Other:     "example.com/both v1.2.0",
+		},
+		{
+			Directive: "require",
+			Path:      "example.com/added",
+			Reason:    "version v1.0.0 is denied",
+			Policy:    gomod.PolicyDenied,
+			Other:     "example.com/added v1.0.0",
+		},
+		{
+			Directive: "replace",
+			Path:      "example.com/forked",
+			Reason:    "replacement version v1.1.0 is denied",
+			Policy:    gomod.PolicyDenied,
+			Current:   "example.com/forked => example.com/fork v1.0.0",
+			Other:     "example.com/forked => example.com/fork v1.1.0",
+		},
+	}, conflicts)
+
+	// The current statements are kept.
+	dep := findRequires(merged, "example.com/dep")
+	require.Len(t, dep, 1)
+
+	assert.Equal(t, "v1.1.0", dep[0].Mod.Version)
+	assert.Empty(t, findRequires(merged, "example.com/added"))
+}
+
+func TestMergeWithOptions_protected(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require (
+	golang.org/x/crypto v0.20.0
+	example.com/auth/sdk v1.1.0
+	example.com/auth/same v1.1.0
+	example.com/dep v1.1.0
+)
+
+replace example.com/auth/sdk => example.com/fork/sdk v1.1.0
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require (
+	golang.org/x/crypto v0.21.0
+	example.com/auth/sdk v1.1.0
+	example.com/auth/same v1.2.0
+	example.com/dep v1.1.0
+)
+
+replace example.com/auth/sdk => example.com/fork/sdk v1.1.0
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require (
+	golang.org/x/crypto v0.20.0
+	example.com/auth/sdk v1.2.0
+	example.com/auth/same v1.2.0
+	example.com/dep v1.2.0
+)
+`)
+
+	merged, conflicts, err := gomod.MergeWithOptions(*current, *other, *ancestor, gomod.Options{
+		Protected: []string{"golang.org/x/crypto", "example.com/auth"},
+	})
+	require.NoError(t, err)
+
+	// Changes on either side conflict, unless both sides agree.
+	assert.Equal(t, []gomod.Conflict{
+		{
+			Directive: "require",
+			Path:      "golang.org/x/crypto",
+			Reason:    "protected module changed",
+			Policy:    gomod.PolicyProtected,
+			Current:   "golang.org/x/crypto v0.21.0",
+			Other:     "golang.org/x/crypto v0.20.0",
+		},
+		{
+			Directive: "require",
+			Path:      "example.com/auth/sdk",
+			Reason:    "protected module changed",
+			Policy:    gomod.PolicyProtected,
+			Current:   "example.com/auth/sdk v1.1.0",
+			Other:     "example.com/auth/sdk v1.2.0",
+		},
+		{
+			Directive: "replace",
+			Path:      "example.com/auth/sdk",
+			Reason:    "protected module changed",
+			Policy:    gomod.PolicyProtected,
+			Current:   "example.com/auth/sdk => example.com/fork/sdk v1.1.0",
+		},
+	}, conflicts)
+
+	// The current statements are kept, and other modules merged as usual.
+	sdk := findRequires(merged, "example.com/auth/sdk")
+	require.Len(t, sdk, 1)
+
+	assert.Equal(t, "v1.1.0", sdk[0].Mod.Version)
+	assert.Len(t, findReplaces(merged, "example.com/auth/sdk"), 1)
+
+	dep := findRequires(merged, "example.com/dep")
+	require.Len(t, dep, 1)
+
+	assert.Equal(t, "v1.2.0", dep[0].Mod.Version)
+}
+
+func TestConflict_String(t *testing.T) {
+	t.Parallel()
+
+	conflict := gomod.Conflict{
+		Directive: "require",
+		Path:      "golang.org/x/crypto",
+		Reason:    "protected module changed",
+		Policy:    gomod.PolicyProtected,
+	}
+
+	assert.Equal(t, "require golang.org/x/crypto: protected module changed (protected policy)", conflict.String())
+}
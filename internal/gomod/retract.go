@@ -0,0 +1,167 @@
+package gomod
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// mergeRetracts merges the retract statements changed and removed by each side
+// of the ancestor into the merged file, treating the retractions as a set keyed
+// on their version interval.
+//
+// Retractions removed on one side are removed, unless the other side changed
+// their rationale. Retractions added on both sides with different rationales
+// keep both rationales. Changed retractions which overlap another retraction,
+// or share an endpoint with it, are then combined into a single retraction
+// covering both intervals, keeping the rationales of each. Intervals which
+// merely follow each other, such as v1.0.0 and v1.0.1, are not combined, as
+// the combined interval would also retract the pre-releases between them.
+func mergeRetracts(merged *modfile.File, ancestor modfile.File, current, other changeset) {
+	retracts := make(map[modfile.VersionInterval]string)
+
+	for _, retract := range ancestor.Retract {
+		retracts[retract.VersionInterval] = retract.Rationale
+	}
+
+	dropRemovedRetracts(retracts, current.removals, other.changes)
+	dropRemovedRetracts(retracts, other.removals, current.changes)
+
+	changed := make(map[modfile.VersionInterval]struct{})
+
+	for _, retract := range current.changes.Retract {
+		retracts[retract.VersionInterval] = retract.Rationale
+		changed[retract.VersionInterval] = struct{}{}
+	}
+
+	for _, retract := range other.changes.Retract {
+		rationale := retract.Rationale
+
+		if _, ok := changed[retract.VersionInterval]; ok {
+			rationale = joinRationales(retracts[retract.VersionInterval], rationale)
+		}
+
+		retracts[retract.VersionInterval] = rationale
+		changed[retract.VersionInterval] = struct{}{}
+	}
+
+	retracts = combineRetracts(retracts, changed)
+
+	// Drop the retractions which are no longer wanted, and update the
+	// rationales of the others, leaving unchanged retractions untouched.
+	for _, retract := range merged.Retract {
+		rationale, ok := retracts[retract.VersionInterval]
+		if !ok {
+			merged.DropRetract(retract.VersionInterval)
+
+			continue
+		}
+
+		setRetract(merged, retract.VersionInterval, rationale)
+		delete(retracts, retract.VersionInterval)
+	}
+
+	// Add the remaining retractions in order, so the result is deterministic.
+	intervals := slices.SortedFunc(maps.Keys(retracts), compareIntervals)
+
+	for _, interval := range intervals {
+		setRetract(merged, interval, retracts[interval])
+	}
+}
+
+// dropRemovedRetracts deletes the retractions removed by one side from the
+// retractions, unless they were changed by the other side.
+func dropRemovedRetracts(retracts map[modfile.VersionInterval]string, removals, changes modfile.File) {
+	for _, retract := range removals.Retract {
+		changed := slices.ContainsFunc(changes.Retract, func(change *modfile.Retract) bool {
+			return change.VersionInterval == retract.VersionInterval
+		})
+
+		if !changed {
+			delete(retracts, retract.VersionInterval)
+		}
+	}
+}
+
+// combineRetracts combines each group of overlapping retractions
+// which contains a changed retraction into a single retraction, covering all of
+// their intervals and keeping all of their rationales.
+func combineRetracts(
+	retracts map[modfile.VersionInterval]string,
+	changed map[modfile.VersionInterval]struct{},
+) map[modfile.VersionInterval]string {
+	intervals := slices.SortedFunc(maps.Keys(retracts), compareIntervals)
+
+	combined := make(map[modfile.VersionInterval]string)
+
+	for len(intervals) > 0 {
+		group := intervals[:1]
+		high := intervals[0].High
+
+		for _, interval := range intervals[1:] {
+			if semver.Compare(interval.Low, high) > 0 {
+				break
+			}
+
+			group = intervals[:len(group)+1]
+
+			if semver.Compare(interval.High, high) > 0 {
+				high = interval.High
+			}
+		}
+
+		intervals = intervals[len(group):]
+
+		groupChanged := slices.ContainsFunc(group, func(interval modfile.VersionInterval) bool {
+			_, ok := changed[interval]
+
+			return ok
+		})
+
+		if len(group) == 1 || !groupChanged {
+			for _, interval := range group {
+				combined[interval] = retracts[interval]
+			}
+
+			continue
+		}
+
+		var rationale string
+
+		for _, interval := range group {
+			rationale = joinRationales(rationale, retracts[interval])
+		}
+
+		combined[modfile.VersionInterval{Low: group[0].Low, High: high}] = rationale
+	}
+
+	return combined
+}
+
+// compareIntervals orders version intervals by their lowest, then highest,
+// versions.
+func compareIntervals(this, other modfile.VersionInterval) int {
+	if c := semver.Compare(this.Low, other.Low); c != 0 {
+		return c
+	}
+
+	return semver.Compare(this.High, other.High)
+}
+
+// joinRationales joins two retraction rationales, one per line, leaving out
+// empty and repeated rationales.
+func joinRationales(this, other string) string {
+	switch {
+	case other == "" || this == other:
+		return this
+	case this == "":
+		return other
+	case slices.Contains(strings.Split(this, "\n"), other):
+		return this
+	default:
+		return this + "\n" + other
+	}
+}
@@ -0,0 +1,270 @@
+package gomod
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+var (
+	// ErrUnknownStrategy is returned when a strategy name is not one of the
+	// built-in strategies.
+	ErrUnknownStrategy = errors.New("gomod: unknown version selection strategy")
+
+	// ErrInvalidStrategyRule is returned when a strategy rule is not of the
+	// form pattern=strategy.
+	ErrInvalidStrategyRule = errors.New("gomod: strategy rule must be pattern=strategy")
+)
+
+// Strategy selects the version of a module to require when both sides of a
+// merge changed its requirement.
+type Strategy interface {
+	// Select returns the version of the module at the path to require, given
+	// the versions the current and other sides require, and the version the
+	// common ancestor requires, which is empty if it doesn't require the
+	// module. An error describes why the versions can't be merged, and is
+	// reported as a conflict.
+	Select(path, current, other, ancestor string) (string, error)
+}
+
+// StrategyFunc is a function implementing [Strategy].
+type StrategyFunc func(path, current, other, ancestor string) (string, error)
+
+// Ensure [StrategyFunc] implements the [Strategy] interface.
+var _ Strategy = StrategyFunc(nil)
+
+// Select calls the function.
+func (f StrategyFunc) Select(path, current, other, ancestor string) (string, error) {
+	return f(path, current, other, ancestor)
+}
+
+// builtin is a built-in strategy, which explains the version it selects.
+type builtin struct {
+	name string
+
+	// selectVersion selects between the current and other versions,
+	// comparing versions with the pseudo-version rule, and explains why.
+	selectVersion func(rule PseudoRule, current, other string) (version, reason string, err error)
+}
+
+// Ensure [builtin] implements the [Strategy] interface.
+var _ Strategy = builtin{}
+
+// Select selects the version, comparing versions with the default
+// pseudo-version rule.
+func (b builtin) Select(_, current, other, _ string) (string, error) {
+	version, _, err := b.selectVersion(PseudoSemver, current, other)
+
+	return version, err
+}
+
+// String returns the name of the strategy.
+func (b builtin) String() string {
+	return b.name
+}
+
+// The built-in strategies.
+var (
+	// Higher selects the higher version, as the go command's minimal version
+	// selection would. It is the default strategy.
+	Higher Strategy = builtin{
+		name: "higher",
+		selectVersion: func(rule PseudoRule, current, other string) (string, string, error) {
+			c, reason := rule.Compare(other, current)
+			if c > 0 {
+				return other, explainOrder(other, current, c, reason), nil
+			}
+
+			return current, explainOrder(current, other, -c, reason), nil
+		},
+	}
+
+	// Lower selects the lower version.
+	Lower Strategy = builtin{
+		name: "lower",
+		selectVersion: func(rule PseudoRule, current, other string) (string, string, error) {
+			c, reason := rule.Compare(other, current)
+			if c < 0 {
+				return other, explainOrder(other, current, c, reason), nil
+			}
+
+			return current, explainOrder(current, other, -c, reason), nil
+		},
+	}
+
+	// PreferCurrent selects the current side's version.
+	PreferCurrent Strategy = builtin{
+		name: "prefer-current",
+		selectVersion: func(_ PseudoRule, current, _ string) (string, string, error) {
+			return current, "the current side's version is preferred", nil
+		},
+	}
+
+	// PreferOther selects the other side's version.
+	PreferOther Strategy = builtin{
+		name: "prefer-other",
+		selectVersion: func(_ PseudoRule, _, other string) (string, string, error) {
+			return other, "the other side's version is preferred", nil
+		},
+	}
+
+	// FailOnDivergence selects the version only if both sides require the
+	// same version, and otherwise reports a conflict.
+	FailOnDivergence Strategy = builtin{
+		name: "fail-on-divergent",
+		selectVersion: func(_ PseudoRule, current, other string) (string, string, error) {
+			if current != other {
+				return "", "", fmt.Errorf("changed to different versions on each side (%s, %s)", current, other)
+			}
+
+			return current, "both sides require the same version", nil
+		},
+	}
+)
+
+// explainOrder explains how the version compares to the other version, as
+// returned by [PseudoRule.Compare] with the reason.
+func explainOrder(version, other string, c int, reason string) string {
+	switch {
+	case c > 0:
+		return fmt.Sprintf("%s is higher than %s, by %s", version, other, reason)
+	case c < 0:
+		return fmt.Sprintf("%s is lower than %s, by %s", version, other, reason)
+	default:
+		return fmt.Sprintf("%s is equivalent to %s, by %s", version, other, reason)
+	}
+}
+
+// strategies maps the name of each built-in strategy to the strategy.
+var strategies = map[string]Strategy{
+	"higher":            Higher,
+	"lower":             Lower,
+	"prefer-current":    PreferCurrent,
+	"prefer-other":      PreferOther,
+	"fail-on-divergent": FailOnDivergence,
+}
+
+// StrategyNames returns the names of the built-in strategies, as accepted by
+// [ParseStrategy], in sorted order.
+func StrategyNames() []string {
+	return slices.Sorted(maps.Keys(strategies))
+}
+
+// ParseStrategy returns the built-in strategy with the name.
+func ParseStrategy(name string) (Strategy, error) {
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf(
+			"%w: %q (must be one of %s)",
+			ErrUnknownStrategy,
+			name,
+			strings.Join(StrategyNames(), ", "),
+		)
+	}
+
+	return strategy, nil
+}
+
+// StrategyRule selects a strategy for the modules matching a pattern.
+type StrategyRule struct {
+	// Pattern is a glob pattern matching module path prefixes, in the same
+	// form as the GOPRIVATE environment variable, such as
+	// "github.com/example/*".
+	Pattern string
+
+	// Strategy is the strategy used for the matching modules.
+	Strategy Strategy
+}
+
+// ParseStrategyRule parses a strategy rule of the form pattern=strategy, where
+// strategy is the name of a built-in strategy.
+func ParseStrategyRule(rule string) (StrategyRule, error) {
+	pattern, name, ok := strings.Cut(rule, "=")
+	if !ok || pattern == "" {
+		return StrategyRule{}, fmt.Errorf("%w: %q", ErrInvalidStrategyRule, rule)
+	}
+
+	strategy, err := ParseStrategy(name)
+	if err != nil {
+		return StrategyRule{}, err
+	}
+
+	return StrategyRule{
+		Pattern:  pattern,
+		Strategy: strategy,
+	}, nil
+}
+
+// Options configures how go.mod files are merged.
+type Options struct {
+	// Strategies select the strategy used for each module, in order of
+	// precedence.
+	Strategies []StrategyRule
+
+	// DefaultStrategy is the strategy used for modules no rule matches. The
+	// higher version is selected if it is nil.
+	DefaultStrategy Strategy
+
+	// Pseudo decides how the built-in strategies, and replacements changed
+	// on both sides, compare pseudo-versions to tagged versions.
+	// [PseudoSemver] is used if it is empty.
+	Pseudo PseudoRule
+
+	// Pinned are patterns, in the same form as those of Strategies, of
+	// modules whose require and replace statements the merge never changes.
+	// The other side's changes to them are ignored.
+	Pinned []string
+
+	// Protected are patterns, in the same form as those of Strategies, of
+	// modules whose require and replace statements are never merged. Any
+	// difference between the sides is reported as a conflict.
+	Protected []string
+
+	// Denied are module versions the merge never selects. Changes which
+	// would require or replace with one are reported as conflicts.
+	Denied []module.Version
+
+	// Logger logs the choices the merge makes. Nothing is logged if it is
+	// nil.
+	Logger *slog.Logger
+}
+
+// strategy returns the strategy used for the module at the path, and the
+// pattern of the rule selecting it, or "default" for the default strategy.
+func (o Options) strategy(path string) (Strategy, string) {
+	for _, rule := range o.Strategies {
+		if module.MatchPrefixPatterns(rule.Pattern, path) {
+			return rule.Strategy, rule.Pattern
+		}
+	}
+
+	if o.DefaultStrategy != nil {
+		return o.DefaultStrategy, "default"
+	}
+
+	return Higher, "default"
+}
+
+// SelectVersion selects the version of the module at the path to require, when
+// both sides changed it, with the module's strategy, explaining the selection.
+// The built-in strategies compare versions with the options' pseudo-version
+// rule. An error describes why the versions can't be merged.
+func (o Options) SelectVersion(path, current, other, ancestor string) (string, string, error) {
+	strategy, pattern := o.strategy(path)
+
+	if b, ok := strategy.(builtin); ok {
+		version, reason, err := b.selectVersion(cmp.Or(o.Pseudo, PseudoSemver), current, other)
+
+		return version, fmt.Sprintf("%s strategy (%s): %s", b.name, pattern, reason), err
+	}
+
+	version, err := strategy.Select(path, current, other, ancestor)
+
+	return version, fmt.Sprintf("custom strategy (%s)", pattern), err
+}
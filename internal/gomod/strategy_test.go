@@ -0,0 +1,162 @@
+package gomod_test
+
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeWithOptions_strategies(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require example.com/dep v1.1.0
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require example.com/dep v1.3.0
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require example.com/dep v1.2.0
+`)
+
+	for name, expected := range map[string]string{
+		"higher":         "v1.3.0",
+		"lower":          "v1.2.0",
+		"prefer-current": "v1.3.0",
+		"prefer-other":   "v1.2.0",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			strategy, err := gomod.ParseStrategy(name)
+			require.NoError(t, err)
+
+			merged, conflicts, err := gomod.MergeWithOptions(*current, *other, *ancestor, gomod.Options{
+				DefaultStrategy: strategy,
+			})
+			require.NoError(t, err)
+			require.Empty(t, conflicts)
+
+			requires := findRequires(merged, "example.com/dep")
+			require.Len(t, requires, 1)
+
+			assert.Equal(t, expected, requires[0].Mod.Version)
+		})
+	}
+}
+
+func TestMergeWithOptions_failOnDivergent(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require (
+	example.com/dep v1.1.0
+	example.com/same v1.1.0
+)
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require (
+	example.com/dep v1.3.0
+	example.com/same v1.2.0
+)
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require (
+	example.com/dep v1.2.0
+	example.com/same v1.2.0
+)
+`)
+
+	merged, conflicts, err := gomod.MergeWithOptions(*current, *other, *ancestor, gomod.Options{
+		DefaultStrategy: gomod.FailOnDivergence,
+	})
+	require.NoError(t, err)
+
+	// Both sides bumping to the same version isn't divergent.
+	require.Len(t, conflicts, 1)
+
+	assert.Equal(t, gomod.Conflict{
+		Directive: "require",
+		Path:      "example.com/dep",
+		Reason:    "changed to different versions on each side (v1.3.0, v1.2.0)",
+		Current:   "example.com/dep v1.3.0",
+		Other:     "example.com/dep v1.2.0",
+	}, conflicts[0])
+
+	// The current requirement is kept.
+	requires := findRequires(merged, "example.com/dep")
+	require.Len(t, requires, 1)
+
+	assert.Equal(t, "v1.3.0", requires[0].Mod.Version)
+}
+
+func TestMergeWithOptions_strategyRules(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require (
+	example.com/regulated/dep v1.1.0
+	example.com/dep v1.1.0
+)
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require (
+	example.com/regulated/dep v1.3.0
+	example.com/dep v1.3.0
+)
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require (
+	example.com/regulated/dep v1.2.0
+	example.com/dep v1.2.0
+)
+`)
+
+	rule, err := gomod.ParseStrategyRule("example.com/regulated=lower")
+	require.NoError(t, err)
+
+	merged, conflicts, err := gomod.MergeWithOptions(*current, *other, *ancestor, gomod.Options{
+		Strategies: []gomod.StrategyRule{rule},
+	})
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	// Patterns match module path prefixes.
+	regulated := findRequires(merged, "example.com/regulated/dep")
+	require.Len(t, regulated, 1)
+
+	assert.Equal(t, "v1.2.0", regulated[0].Mod.Version)
+
+	// Other modules use the default strategy, selecting the higher version.
+	dep := findRequires(merged, "example.com/dep")
+	require.Len(t, dep, 1)
+
+	assert.Equal(t, "v1.3.0", dep[0].Mod.Version)
+}
+
+func TestParseStrategyRule(t *testing.T) {
+	t.Parallel()
+
+	_, err := gomod.ParseStrategyRule("example.com/*")
+	require.ErrorIs(t, err, gomod.ErrInvalidStrategyRule)
+
+	_, err = gomod.ParseStrategyRule("example.com/*=newest")
+	require.ErrorIs(t, err, gomod.ErrUnknownStrategy)
+}
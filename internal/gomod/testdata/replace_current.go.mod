@@ -0,0 +1,14 @@
+module example.com/m
+
+go 1.22
+
+require (
+	example.com/dep v1.3.0
+	example.com/other v1.0.0
+)
+
+replace (
+	example.com/dep => example.com/fork v1.1.0
+	example.com/dep v1.2.0 => example.com/fork v1.2.0-patched
+	example.com/dep v1.3.0 => ../dep
+)
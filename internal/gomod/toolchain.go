@@ -0,0 +1,74 @@
+package gomod
+
+import (
+	"go/version"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Special toolchain names, which don't name a Go release.
+const (
+	// defaultToolchain selects the default toolchain, as if there were no
+	// toolchain statement.
+	defaultToolchain = "default"
+
+	// localToolchain selects the locally installed toolchain.
+	localToolchain = "local"
+)
+
+// isNamedToolchain reports whether the toolchain names a Go release, such as
+// "go1.22.3", rather than being one of the special toolchain names.
+func isNamedToolchain(name string) bool {
+	return name != defaultToolchain && name != localToolchain && version.IsValid(name)
+}
+
+// compareGoVersions compares two go statement versions, such as "1.22.3" or
+// "1.23rc1", in the order of the Go releases they name, as the go command does.
+func compareGoVersions(this, other string) int {
+	return version.Compare("go"+this, "go"+other)
+}
+
+// compareToolchains compares two toolchain names. The special toolchain names
+// don't require any particular release, so are lower than any named toolchain.
+func compareToolchains(this, other string) int {
+	thisNamed := isNamedToolchain(this)
+	otherNamed := isNamedToolchain(other)
+
+	switch {
+	case thisNamed && otherNamed:
+		return version.Compare(this, other)
+	case thisNamed:
+		return 1
+	case otherNamed:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// maxToolchain returns the higher of the two toolchain statements, preferring
+// the current statement when they are equivalent. Either statement may be nil
+// if that side doesn't set a toolchain.
+func maxToolchain(current, other *modfile.Toolchain) *modfile.Toolchain {
+	if current == nil {
+		return other
+	}
+
+	if other == nil || compareToolchains(current.Name, other.Name) >= 0 {
+		return current
+	}
+
+	return other
+}
+
+// dropRedundantToolchain drops the toolchain statement if it doesn't require a
+// newer release than the go version already does, as the go command would.
+func dropRedundantToolchain(f *modfile.File) {
+	if f.Toolchain == nil || f.Go == nil || !isNamedToolchain(f.Toolchain.Name) {
+		return
+	}
+
+	if version.Compare(f.Toolchain.Name, "go"+f.Go.Version) <= 0 {
+		f.DropToolchainStmt()
+	}
+}
@@ -0,0 +1,136 @@
+package gomod
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// ErrUnknownPseudoRule is returned when a pseudo-version rule name is not one
+// of the supported rules.
+var ErrUnknownPseudoRule = errors.New("gomod: unknown pseudo-version rule")
+
+// PseudoRule decides how pseudo-versions, such as
+// v1.2.4-0.20240102150405-abcdef123456, compare to tagged versions, when
+// versions are compared to select between them.
+//
+// Pseudo-versions are compared to each other in the same way whatever the
+// rule: by their base versions if both have one, and otherwise by the time
+// of their commits, so a pseudo-version without a base version, such as
+// v0.0.0-20240102150405-abcdef123456, is newer than those of earlier commits.
+type PseudoRule string
+
+// The supported pseudo-version rules.
+const (
+	// PseudoSemver orders pseudo-versions as the go command does: a
+	// pseudo-version is newer than the version it is based on, and older than
+	// any later version, including pre-releases. It is the default rule.
+	PseudoSemver PseudoRule = "semver"
+
+	// PseudoPreferRelease makes a release newer than any pseudo-version, even
+	// one based on it or a later version.
+	PseudoPreferRelease PseudoRule = "prefer-release"
+
+	// PseudoPreferPseudo makes a pseudo-version newer than any pre-release,
+	// which it may have been built after without being based on.
+	PseudoPreferPseudo PseudoRule = "prefer-pseudo"
+)
+
+// pseudoRules holds the supported pseudo-version rules.
+var pseudoRules = map[string]PseudoRule{
+	string(PseudoSemver):        PseudoSemver,
+	string(PseudoPreferRelease): PseudoPreferRelease,
+	string(PseudoPreferPseudo):  PseudoPreferPseudo,
+}
+
+// PseudoRuleNames returns the names of the supported pseudo-version rules, as
+// accepted by [ParsePseudoRule], in sorted order.
+func PseudoRuleNames() []string {
+	return slices.Sorted(maps.Keys(pseudoRules))
+}
+
+// ParsePseudoRule returns the pseudo-version rule with the name.
+func ParsePseudoRule(name string) (PseudoRule, error) {
+	rule, ok := pseudoRules[name]
+	if !ok {
+		return "", fmt.Errorf(
+			"%w: %q (must be one of %s)",
+			ErrUnknownPseudoRule,
+			name,
+			strings.Join(PseudoRuleNames(), ", "),
+		)
+	}
+
+	return rule, nil
+}
+
+// Compare compares two versions of a module, returning -1, 0 or 1 as v is
+// older than, the same as, or newer than w, and what decided the comparison.
+// The empty rule is [PseudoSemver].
+func (r PseudoRule) Compare(v, w string) (int, string) {
+	vPseudo := module.IsPseudoVersion(v)
+	wPseudo := module.IsPseudoVersion(w)
+
+	switch {
+	case vPseudo && wPseudo:
+		return comparePseudo(v, w)
+	case vPseudo:
+		return r.comparePseudoTagged(v, w)
+	case wPseudo:
+		c, reason := r.comparePseudoTagged(w, v)
+
+		return -c, reason
+	default:
+		return semver.Compare(v, w), "semantic version order"
+	}
+}
+
+// comparePseudo compares two pseudo-versions, by their base versions if both
+// have one, and otherwise by the time of their commits.
+func comparePseudo(v, w string) (int, string) {
+	vBase, _ := module.PseudoVersionBase(v)
+	wBase, _ := module.PseudoVersionBase(w)
+
+	if vBase != "" && wBase != "" && vBase != wBase {
+		return semver.Compare(v, w), fmt.Sprintf("pseudo-version base versions %s and %s", vBase, wBase)
+	}
+
+	vTime, vErr := module.PseudoVersionTime(v)
+	wTime, wErr := module.PseudoVersionTime(w)
+
+	if vErr == nil && wErr == nil && !vTime.Equal(wTime) {
+		return vTime.Compare(wTime), fmt.Sprintf(
+			"pseudo-version commit times %s and %s",
+			vTime.Format(time.RFC3339),
+			wTime.Format(time.RFC3339),
+		)
+	}
+
+	return semver.Compare(v, w), "semantic version order"
+}
+
+// comparePseudoTagged compares the pseudo-version to the tagged version.
+func (r PseudoRule) comparePseudoTagged(pseudo, tagged string) (int, string) {
+	release := semver.Prerelease(tagged) == ""
+
+	switch {
+	case r == PseudoPreferRelease && release:
+		return -1, fmt.Sprintf("%s rule, as %s is a release", r, tagged)
+	case r == PseudoPreferPseudo && !release:
+		return 1, fmt.Sprintf("%s rule, as %s is a pre-release", r, tagged)
+	}
+
+	base, _ := module.PseudoVersionBase(pseudo)
+
+	if base != "" && semver.Compare(base, tagged) >= 0 {
+		return 1, fmt.Sprintf("%s being based on %s", pseudo, base)
+	}
+
+	return semver.Compare(pseudo, tagged), "semantic version order"
+}
@@ -0,0 +1,144 @@
+package gomod_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPseudoRule_Compare(t *testing.T) {
+	t.Parallel()
+
+	const (
+		// pseudo is based on v1.2.3, from a commit in 2024.
+		pseudo = "v1.2.4-0.20240102150405-abcdef123456"
+
+		// older is based on v1.2.3, from an earlier commit in 2023.
+		older = "v1.2.4-0.20230102150405-abcdef123456"
+
+		// untagged has no base version, from a commit in 2025.
+		untagged = "v0.0.0-20250102150405-abcdef123456"
+	)
+
+	for _, test := range []struct {
+		name     string
+		rule     gomod.PseudoRule
+		v, w     string
+		expected int
+	}{
+		{"tagged", gomod.PseudoSemver, "v1.3.0", "v1.2.0", 1},
+		{"pre-release", gomod.PseudoSemver, "v1.3.0-rc.1", "v1.3.0", -1},
+		{"pseudo after base", gomod.PseudoSemver, pseudo, "v1.2.3", 1},
+		{"pseudo before next release", gomod.PseudoSemver, pseudo, "v1.2.4", -1},
+		{"pseudo before next pre-release", gomod.PseudoSemver, pseudo, "v1.2.4-rc.1", -1},
+		{"pseudo commit times", gomod.PseudoSemver, older, pseudo, -1},
+		{"pseudo bases", gomod.PseudoSemver, "v1.3.1-0.20230102150405-abcdef123456", pseudo, 1},
+		{"untagged pseudo commit time", gomod.PseudoSemver, untagged, pseudo, 1},
+		{"untagged pseudo before tags", gomod.PseudoSemver, untagged, "v0.1.0-rc.1", -1},
+		{"prefer-release", gomod.PseudoPreferRelease, pseudo, "v1.2.3", -1},
+		{"prefer-release pre-release", gomod.PseudoPreferRelease, pseudo, "v1.2.4-rc.1", -1},
+		{"prefer-release newer pre-release", gomod.PseudoPreferRelease, pseudo, "v1.2.3-rc.1", 1},
+		{"prefer-pseudo", gomod.PseudoPreferPseudo, untagged, "v0.1.0-rc.1", 1},
+		{"prefer-pseudo release", gomod.PseudoPreferPseudo, pseudo, "v1.2.4", -1},
+		{"default rule", "", pseudo, "v1.2.3", 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, reason := test.rule.Compare(test.v, test.w)
+			assert.Equal(t, test.expected, c, reason)
+			assert.NotEmpty(t, reason)
+
+			// Comparisons are antisymmetric.
+			reversed, _ := test.rule.Compare(test.w, test.v)
+			assert.Equal(t, -test.expected, reversed)
+		})
+	}
+}
+
+func TestParsePseudoRule(t *testing.T) {
+	t.Parallel()
+
+	rule, err := gomod.ParsePseudoRule("prefer-release")
+	require.NoError(t, err)
+
+	assert.Equal(t, gomod.PseudoPreferRelease, rule)
+
+	_, err = gomod.ParsePseudoRule("newest")
+	require.ErrorIs(t, err, gomod.ErrUnknownPseudoRule)
+}
+
+func TestMergeWithOptions_pseudoVersions(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require example.com/dep v1.2.2
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require example.com/dep v1.2.3
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require example.com/dep v1.2.4-0.20240102150405-abcdef123456
+`)
+
+	for rule, expected := range map[gomod.PseudoRule]string{
+		gomod.PseudoSemver:        "v1.2.4-0.20240102150405-abcdef123456",
+		gomod.PseudoPreferRelease: "v1.2.3",
+	} {
+		t.Run(string(rule), func(t *testing.T) {
+			t.Parallel()
+
+			merged, conflicts, err := gomod.MergeWithOptions(*current, *other, *ancestor, gomod.Options{
+				Pseudo: rule,
+			})
+			require.NoError(t, err)
+			require.Empty(t, conflicts)
+
+			requires := findRequires(merged, "example.com/dep")
+			require.Len(t, requires, 1)
+
+			assert.Equal(t, expected, requires[0].Mod.Version)
+		})
+	}
+}
+
+func TestMergeWithOptions_explainsSelection(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseModFile(t, `module example.com/m
+
+require example.com/dep v1.2.2
+`)
+
+	current := parseModFile(t, `module example.com/m
+
+require example.com/dep v1.2.3
+`)
+
+	other := parseModFile(t, `module example.com/m
+
+require example.com/dep v1.2.4-0.20240102150405-abcdef123456
+`)
+
+	var logs bytes.Buffer
+
+	_, conflicts, err := gomod.MergeWithOptions(*current, *other, *ancestor, gomod.Options{
+		Pseudo: gomod.PseudoPreferRelease,
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	assert.Contains(t, logs.String(), "selected=v1.2.3")
+	assert.Contains(t, logs.String(), `reason="higher strategy (default): v1.2.3 is higher than `+
+		`v1.2.4-0.20240102150405-abcdef123456, by prefer-release rule, as v1.2.3 is a release"`)
+}
@@ -0,0 +1,102 @@
+package gosum
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+)
+
+// Conflict describes a go.sum key which has different hashes on the current and
+// other sides of a merge, or, for security conflicts, whose merged hash differs
+// from the hash recorded by a checksum database.
+type Conflict struct {
+	Key     GoSumKey
+	Current GoSumHash
+	Other   GoSumHash
+
+	// Database is the name of the checksum database the merged hash was
+	// verified against, for security conflicts. The merged hash is then held
+	// in Current.
+	Database string
+
+	// Expected is the hash the checksum database records for the key, for
+	// security conflicts.
+	Expected GoSumHash
+}
+
+// Ensure [Conflict] implements the [fmt.Stringer] interface.
+var _ fmt.Stringer = Conflict{}
+
+// String returns a human-readable description of the conflict.
+func (c Conflict) String() string {
+	if c.Security() {
+		return fmt.Sprintf(
+			"%s: SECURITY ERROR: hash mismatch with checksum database %s (%s != %s)",
+			c.Key,
+			c.Database,
+			c.Current,
+			c.Expected,
+		)
+	}
+
+	return fmt.Sprintf("%s: hash mismatch (%s != %s)", c.Key, c.Current, c.Other)
+}
+
+// Security reports whether the conflict is a mismatch with a checksum
+// database, rather than between the sides of the merge.
+func (c Conflict) Security() bool {
+	return c.Database != ""
+}
+
+// FormatConflicts returns a string representation of the merged go.sum file,
+// like [GoSum.String], with a git-style conflict block holding both hashes for
+// each conflicting key. The conflict blocks of security conflicts hold the
+// merged hash, labelled "merged", and the checksum database's hash, labelled
+// with the database's name.
+func FormatConflicts(merged GoSum, conflicts []Conflict, labels markers.Labels) string {
+	var b strings.Builder
+
+	conflicting := make(map[GoSumKey]Conflict, len(conflicts))
+
+	for _, conflict := range conflicts {
+		conflicting[conflict.Key] = conflict
+	}
+
+	keys := slices.Collect(maps.Keys(merged))
+
+	for key := range conflicting {
+		if _, ok := merged[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.SortFunc(keys, CompareKeys)
+
+	for _, key := range keys {
+		lines := []string{formatLine(key, merged[key])}
+
+		if conflict, ok := conflicting[key]; ok && conflict.Security() {
+			lines = markers.Block(
+				[]string{formatLine(key, conflict.Current)},
+				[]string{formatLine(key, conflict.Expected)},
+				markers.Labels{Current: "merged", Other: conflict.Database},
+			)
+		} else if ok {
+			lines = markers.Block(
+				[]string{formatLine(key, conflict.Current)},
+				[]string{formatLine(key, conflict.Other)},
+				labels,
+			)
+		}
+
+		for _, line := range lines {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
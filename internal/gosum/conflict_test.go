@@ -0,0 +1,91 @@
+package gosum_test
+
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gosum"
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatConflicts(t *testing.T) {
+	t.Parallel()
+
+	merged := gosum.GoSum{
+		gosum.GoSumKey{
+			ModulePath: "golang.org/x/mod",
+			Version:    "v0.17.0",
+			Path:       "go.mod",
+		}: "h1:hTbmBsO62+eylJbnUtE2MGJUyE7QWk4xUqPFrRgJ+7c=",
+	}
+
+	conflicts := []gosum.Conflict{{
+		Key: gosum.GoSumKey{
+			ModulePath: "golang.org/x/mod",
+			Version:    "v0.17.0",
+		},
+		Current: "h1:zY54UmvipHiNd+pm+m0x9KhZ9hl1/7QNMyxXbc6ICqA=",
+		Other:   "h1:zY54UmvipHiNd+pm+m0x9KhZ9hl1/7QNMyxXbc6ICqB=",
+	}}
+
+	expected := `<<<<<<< HEAD
+golang.org/x/mod v0.17.0 h1:zY54UmvipHiNd+pm+m0x9KhZ9hl1/7QNMyxXbc6ICqA=
+=======
+golang.org/x/mod v0.17.0 h1:zY54UmvipHiNd+pm+m0x9KhZ9hl1/7QNMyxXbc6ICqB=
+>>>>>>> feature
+golang.org/x/mod v0.17.0/go.mod h1:hTbmBsO62+eylJbnUtE2MGJUyE7QWk4xUqPFrRgJ+7c=
+`
+
+	actual := gosum.FormatConflicts(merged, conflicts, markers.Labels{
+		Current: "HEAD",
+		Other:   "feature",
+	})
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestConflict_String(t *testing.T) {
+	t.Parallel()
+
+	conflict := gosum.Conflict{
+		Key: gosum.GoSumKey{
+			ModulePath: "golang.org/x/mod",
+			Version:    "v0.17.0",
+			Path:       "go.mod",
+		},
+		Current: "h1:current=",
+		Other:   "h1:other=",
+	}
+
+	assert.Equal(t, "golang.org/x/mod@v0.17.0/go.mod: hash mismatch (h1:current= != h1:other=)", conflict.String())
+}
+
+func TestFormatConflicts_security(t *testing.T) {
+	t.Parallel()
+
+	key := gosum.GoSumKey{ModulePath: "example.com/dep", Version: "v1.0.0"}
+
+	conflict := gosum.Conflict{
+		Key:      key,
+		Current:  "h1:tampered=",
+		Database: "sum.golang.org",
+		Expected: "h1:dep=",
+	}
+
+	assert.Equal(t,
+		"example.com/dep@v1.0.0: SECURITY ERROR: hash mismatch with checksum database sum.golang.org (h1:tampered= != h1:dep=)",
+		conflict.String(),
+	)
+
+	actual := gosum.FormatConflicts(gosum.GoSum{}, []gosum.Conflict{conflict}, markers.Labels{
+		Current: "HEAD",
+		Other:   "feature",
+	})
+
+	assert.Equal(t, `<<<<<<< merged
+example.com/dep v1.0.0 h1:tampered=
+=======
+example.com/dep v1.0.0 h1:dep=
+>>>>>>> sum.golang.org
+`, actual)
+}
@@ -6,10 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"slices"
 	"strings"
 
-	"golang.org/x/exp/maps"
 	"golang.org/x/mod/semver"
 )
 
@@ -32,6 +32,21 @@ type GoSumKey struct {
 	Path       string
 }
 
+// Ensure [GoSumKey] implements the [fmt.Stringer] interface.
+var _ fmt.Stringer = GoSumKey{}
+
+// String returns the key in the form "<module path>@<version>", followed by
+// "/<path>" if the key has a non-empty path.
+func (k GoSumKey) String() string {
+	key := k.ModulePath + "@" + k.Version
+
+	if k.Path != "" {
+		key += "/" + k.Path
+	}
+
+	return key
+}
+
 // CompareKeys compares two GoSumKeys.
 //
 // Performs lexicographical ordering of module paths, then semver comparison of
@@ -102,19 +117,23 @@ var _ fmt.Stringer = GoSum{}
 func (g GoSum) String() string {
 	var b strings.Builder
 
-	keys := maps.Keys(g)
-	slices.SortFunc(keys, CompareKeys)
+	keys := slices.SortedFunc(maps.Keys(g), CompareKeys)
 
 	for _, key := range keys {
-		version := key.Version
-		hash := g[key]
+		b.WriteString(formatLine(key, g[key]))
+		b.WriteString("\n")
+	}
 
-		if key.Path != "" {
-			version += "/" + key.Path
-		}
+	return b.String()
+}
+
+// formatLine formats a single go.sum line, without its trailing newline.
+func formatLine(key GoSumKey, hash GoSumHash) string {
+	version := key.Version
 
-		fmt.Fprintf(&b, "%s %s %s\n", key.ModulePath, version, hash)
+	if key.Path != "" {
+		version += "/" + key.Path
 	}
 
-	return b.String()
+	return fmt.Sprintf("%s %s %s", key.ModulePath, version, hash)
 }
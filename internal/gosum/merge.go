@@ -1,6 +1,9 @@
 package gosum
 
-import "maps"
+import (
+	"maps"
+	"slices"
+)
 
 // Merge merges two go.sum files together. The current go.sum file is the one
 // that is being modified, the other go.sum file is the one that is being merged
@@ -10,6 +13,19 @@ import "maps"
 // If there are inconsistent hashes between the current and other go.sum files,
 // an error is returned.
 func Merge(current GoSum, other GoSum, ancestor GoSum) (GoSum, error) {
+	merged, conflicts := MergeConflicts(current, other, ancestor)
+	if len(conflicts) != 0 {
+		return nil, ErrHashMismatch
+	}
+
+	return merged, nil
+}
+
+// MergeConflicts merges two go.sum files together like [Merge], but rather than
+// failing on inconsistent hashes between the current and other go.sum files, it
+// leaves the inconsistent keys out of the merged go.sum file and returns them
+// as conflicts.
+func MergeConflicts(current GoSum, other GoSum, ancestor GoSum) (GoSum, []Conflict) {
 	currentAdded, currentModified, currentRemoved := Diff(current, ancestor)
 	otherAdded, otherModified, otherRemoved := Diff(other, ancestor)
 
@@ -17,11 +33,25 @@ func Merge(current GoSum, other GoSum, ancestor GoSum) (GoSum, error) {
 	currentAddedModified := overlay(currentAdded, currentModified)
 	otherAddedModified := overlay(otherAdded, otherModified)
 
+	var conflicts []Conflict
+
 	_, modified, _ := Diff(currentAddedModified, otherAddedModified)
-	if len(modified) != 0 {
-		return nil, ErrHashMismatch
+
+	for key := range modified {
+		conflicts = append(conflicts, Conflict{
+			Key:     key,
+			Current: currentAddedModified[key],
+			Other:   otherAddedModified[key],
+		})
+
+		delete(currentAddedModified, key)
+		delete(otherAddedModified, key)
 	}
 
+	slices.SortFunc(conflicts, func(this, other Conflict) int {
+		return CompareKeys(this.Key, other.Key)
+	})
+
 	allAddedModified := overlay(currentAddedModified, otherAddedModified)
 	allRemoved := overlay(currentRemoved, otherRemoved)
 
@@ -44,7 +74,12 @@ func Merge(current GoSum, other GoSum, ancestor GoSum) (GoSum, error) {
 		delete(res, key)
 	}
 
-	return res, nil
+	// Neither hash of a conflicting key can be trusted.
+	for _, conflict := range conflicts {
+		delete(res, conflict.Key)
+	}
+
+	return res, conflicts
 }
 
 // overlay overlays the sum go.sum file over the ancestor go.sum file.
@@ -36,3 +36,34 @@ func loadGoSum(t *testing.T, path string) gosum.GoSum {
 
 	return goSum
 }
+
+func TestMerge_hashMismatch(t *testing.T) {
+	t.Parallel()
+
+	key := gosum.GoSumKey{ModulePath: "golang.org/x/mod", Version: "v0.17.0"}
+
+	current := gosum.GoSum{key: "h1:current="}
+	other := gosum.GoSum{key: "h1:other="}
+
+	_, err := gosum.Merge(current, other, gosum.GoSum{})
+	require.ErrorIs(t, err, gosum.ErrHashMismatch)
+}
+
+func TestMergeConflicts(t *testing.T) {
+	t.Parallel()
+
+	conflictKey := gosum.GoSumKey{ModulePath: "golang.org/x/mod", Version: "v0.17.0"}
+	agreedKey := gosum.GoSumKey{ModulePath: "golang.org/x/mod", Version: "v0.17.0", Path: "go.mod"}
+
+	current := gosum.GoSum{conflictKey: "h1:current=", agreedKey: "h1:agreed="}
+	other := gosum.GoSum{conflictKey: "h1:other=", agreedKey: "h1:agreed="}
+
+	merged, conflicts := gosum.MergeConflicts(current, other, gosum.GoSum{})
+
+	assert.Equal(t, gosum.GoSum{agreedKey: "h1:agreed="}, merged)
+	assert.Equal(t, []gosum.Conflict{{
+		Key:     conflictKey,
+		Current: "h1:current=",
+		Other:   "h1:other=",
+	}}, conflicts)
+}
@@ -0,0 +1,122 @@
+package gosum
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// ErrNotInModCache is returned when a module version's hash is not in the
+// module cache.
+var ErrNotInModCache = errors.New("gosum: hash not in module cache")
+
+// ModCache reads the hashes of downloaded module versions from a module cache,
+// without network access.
+type ModCache struct {
+	// Dir is the module cache directory, such as $GOPATH/pkg/mod.
+	Dir string
+}
+
+// DefaultModCache returns the module cache the go command uses: $GOMODCACHE,
+// or the pkg/mod directory of the first $GOPATH entry.
+func DefaultModCache() ModCache {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return ModCache{Dir: dir}
+	}
+
+	gopath := filepath.SplitList(build.Default.GOPATH)
+	if len(gopath) == 0 {
+		return ModCache{}
+	}
+
+	return ModCache{Dir: filepath.Join(gopath[0], "pkg", "mod")}
+}
+
+// Hash returns the hash of the key from the module cache. Module hashes are
+// read from the .ziphash file of the module version, and go.mod hashes are
+// computed from its .mod file, in the same way as the go command.
+func (c ModCache) Hash(key GoSumKey) (GoSumHash, error) {
+	if c.Dir == "" {
+		return "", fmt.Errorf("%w: %s: no module cache", ErrNotInModCache, key)
+	}
+
+	escapedPath, err := module.EscapePath(key.ModulePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to escape module path (%s): %w", key.ModulePath, err)
+	}
+
+	escapedVersion, err := module.EscapeVersion(key.Version)
+	if err != nil {
+		return "", fmt.Errorf("failed to escape module version (%s): %w", key.Version, err)
+	}
+
+	prefix := filepath.Join(c.Dir, "cache", "download", filepath.FromSlash(escapedPath), "@v", escapedVersion)
+
+	switch key.Path {
+	case "":
+		data, err := os.ReadFile(prefix + ".ziphash")
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrNotInModCache, key)
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to read module hash (%s): %w", key, err)
+		}
+
+		return GoSumHash(strings.TrimSpace(string(data))), nil
+	case goModPath:
+		modFile := prefix + ".mod"
+
+		if _, err := os.Stat(modFile); errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrNotInModCache, key)
+		}
+
+		hash, err := dirhash.Hash1([]string{goModPath}, func(string) (io.ReadCloser, error) {
+			return os.Open(modFile)
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to hash go.mod file (%s): %w", key, err)
+		}
+
+		return GoSumHash(hash), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrNotInModCache, key)
+	}
+}
+
+// Fill returns the go.sum file with the hashes of the keys added from the
+// module cache, and the keys whose hashes couldn't be found.
+func Fill(sum GoSum, keys []GoSumKey, cache ModCache) (GoSum, []GoSumKey) {
+	filled := make(GoSum, len(sum)+len(keys))
+
+	for key, hash := range sum {
+		filled[key] = hash
+	}
+
+	var notFound []GoSumKey
+
+	for _, key := range keys {
+		// Never replace a hash already in the go.sum file.
+		if _, ok := filled[key]; ok {
+			continue
+		}
+
+		hash, err := cache.Hash(key)
+		if err != nil {
+			notFound = append(notFound, key)
+
+			continue
+		}
+
+		filled[key] = hash
+	}
+
+	return filled, notFound
+}
@@ -0,0 +1,57 @@
+package gosum_test
+
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gosum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testModCache holds the downloaded files of github.com/spf13/cobra v1.8.0.
+var testModCache = gosum.ModCache{Dir: "testdata/modcache"}
+
+var (
+	cobraKey    = gosum.GoSumKey{ModulePath: "github.com/spf13/cobra", Version: "v1.8.0"}
+	cobraModKey = gosum.GoSumKey{ModulePath: "github.com/spf13/cobra", Version: "v1.8.0", Path: "go.mod"}
+)
+
+func TestModCache_Hash(t *testing.T) {
+	t.Parallel()
+
+	hash, err := testModCache.Hash(cobraKey)
+	require.NoError(t, err)
+	assert.Equal(t, gosum.GoSumHash("h1:7aJaZx1B85qltLMc546zn58BxxfZdR/W22ej9CFoEf0="), hash)
+
+	hash, err = testModCache.Hash(cobraModKey)
+	require.NoError(t, err)
+	assert.Equal(t, gosum.GoSumHash("h1:WXLWApfZ71AjXPya3WOlMsY9yMs7YeiHhFVlvLyhcho="), hash)
+}
+
+func TestModCache_Hash_notFound(t *testing.T) {
+	t.Parallel()
+
+	_, err := testModCache.Hash(gosum.GoSumKey{ModulePath: "github.com/spf13/cobra", Version: "v1.9.0"})
+	require.ErrorIs(t, err, gosum.ErrNotInModCache)
+
+	// Module paths are escaped in the module cache, so this is not cobra.
+	_, err = testModCache.Hash(gosum.GoSumKey{ModulePath: "github.com/Spf13/cobra", Version: "v1.8.0"})
+	require.ErrorIs(t, err, gosum.ErrNotInModCache)
+}
+
+func TestFill(t *testing.T) {
+	t.Parallel()
+
+	missing := gosum.GoSumKey{ModulePath: "example.com/missing", Version: "v1.0.0", Path: "go.mod"}
+
+	// Existing hashes are never replaced.
+	sum := gosum.GoSum{cobraKey: "h1:existing="}
+
+	filled, notFound := gosum.Fill(sum, []gosum.GoSumKey{cobraKey, cobraModKey, missing}, testModCache)
+
+	assert.Equal(t, gosum.GoSum{
+		cobraKey:    "h1:existing=",
+		cobraModKey: "h1:WXLWApfZ71AjXPya3WOlMsY9yMs7YeiHhFVlvLyhcho=",
+	}, filled)
+	assert.Equal(t, []gosum.GoSumKey{missing}, notFound)
+}
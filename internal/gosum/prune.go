@@ -0,0 +1,90 @@
+package gosum
+
+import (
+	"maps"
+	"slices"
+
+	"golang.org/x/mod/module"
+)
+
+// Prune returns the go.sum file without the hashes already held by any of the
+// member go.sum files. The go command checks a workspace's go.work.sum file
+// after the go.sum files of its modules, so it never duplicates their hashes.
+//
+// Keys whose hash differs from a member's hash are kept, so the mismatch is
+// still reported by the go command.
+func Prune(sum GoSum, members ...GoSum) GoSum {
+	pruned := make(GoSum, len(sum))
+
+	for key, hash := range sum {
+		if !inMember(key, hash, members) {
+			pruned[key] = hash
+		}
+	}
+
+	return pruned
+}
+
+// inMember reports whether any of the member go.sum files hold the hash for the
+// key.
+func inMember(key GoSumKey, hash GoSumHash, members []GoSum) bool {
+	for _, member := range members {
+		if memberHash, ok := member[key]; ok && memberHash == hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PruneVersions returns the go.sum file without the hashes of the module
+// versions, and the keys of the hashes pruned, in sorted order. Hashes the kept
+// go.sum file holds are not pruned, so only hashes a merge introduced are.
+func PruneVersions(sum, kept GoSum, versions []module.Version) (GoSum, []GoSumKey) {
+	pruned := make(GoSum, len(sum))
+
+	var keys []GoSumKey
+
+	for key, hash := range sum {
+		_, ok := kept[key]
+		version := module.Version{Path: key.ModulePath, Version: key.Version}
+
+		if !ok && slices.Contains(versions, version) {
+			keys = append(keys, key)
+
+			continue
+		}
+
+		pruned[key] = hash
+	}
+
+	slices.SortFunc(keys, CompareKeys)
+
+	return pruned, keys
+}
+
+// KeepAdded returns the pruned go.sum file with the hashes of the merged go.sum
+// file which are not in the common ancestor restored, and the keys of the
+// restored hashes, in sorted order. It undoes pruning against a go.mod file
+// which may not hold either side's changes yet, so neither side's new hashes
+// are lost.
+func KeepAdded(pruned, merged, ancestor GoSum) (GoSum, []GoSumKey) {
+	kept := maps.Clone(pruned)
+
+	var keys []GoSumKey
+
+	for key, hash := range merged {
+		if _, ok := ancestor[key]; ok {
+			continue
+		}
+
+		if _, ok := kept[key]; !ok {
+			kept[key] = hash
+			keys = append(keys, key)
+		}
+	}
+
+	slices.SortFunc(keys, CompareKeys)
+
+	return kept, keys
+}
@@ -0,0 +1,111 @@
+package gosum_test
+
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gosum"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/mod/module"
+)
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+
+	shared := gosum.GoSumKey{ModulePath: "example.com/shared", Version: "v1.0.0"}
+	mismatched := gosum.GoSumKey{ModulePath: "example.com/mismatched", Version: "v1.0.0"}
+	workspace := gosum.GoSumKey{ModulePath: "example.com/workspace", Version: "v1.0.0", Path: "go.mod"}
+
+	sum := gosum.GoSum{
+		shared:     "h1:shared=",
+		mismatched: "h1:workspace=",
+		workspace:  "h1:workspace=",
+	}
+
+	api := gosum.GoSum{
+		shared: "h1:shared=",
+	}
+
+	cmd := gosum.GoSum{
+		mismatched: "h1:member=",
+	}
+
+	pruned := gosum.Prune(sum, api, cmd)
+
+	assert.Equal(t, gosum.GoSum{
+		mismatched: "h1:workspace=",
+		workspace:  "h1:workspace=",
+	}, pruned)
+}
+
+func TestPrune_noMembers(t *testing.T) {
+	t.Parallel()
+
+	sum := gosum.GoSum{
+		{ModulePath: "example.com/m", Version: "v1.0.0"}: "h1:m=",
+	}
+
+	assert.Equal(t, sum, gosum.Prune(sum))
+}
+
+func TestPruneVersions(t *testing.T) {
+	t.Parallel()
+
+	denied := gosum.GoSumKey{ModulePath: "example.com/m", Version: "v1.2.0"}
+	deniedGoMod := gosum.GoSumKey{ModulePath: "example.com/m", Version: "v1.2.0", Path: "go.mod"}
+	kept := gosum.GoSumKey{ModulePath: "example.com/kept", Version: "v1.2.0"}
+	allowed := gosum.GoSumKey{ModulePath: "example.com/m", Version: "v1.1.0"}
+
+	sum := gosum.GoSum{
+		denied:      "h1:denied=",
+		deniedGoMod: "h1:deniedgomod=",
+		kept:        "h1:kept=",
+		allowed:     "h1:allowed=",
+	}
+
+	current := gosum.GoSum{
+		kept: "h1:kept=",
+	}
+
+	pruned, keys := gosum.PruneVersions(sum, current, []module.Version{
+		{Path: "example.com/m", Version: "v1.2.0"},
+		{Path: "example.com/kept", Version: "v1.2.0"},
+	})
+
+	assert.Equal(t, gosum.GoSum{
+		kept:    "h1:kept=",
+		allowed: "h1:allowed=",
+	}, pruned)
+	assert.Equal(t, []gosum.GoSumKey{denied, deniedGoMod}, keys)
+}
+
+func TestKeepAdded(t *testing.T) {
+	t.Parallel()
+
+	old := gosum.GoSumKey{ModulePath: "example.com/dep", Version: "v1.0.0"}
+	bumped := gosum.GoSumKey{ModulePath: "example.com/dep", Version: "v1.1.0"}
+	bumpedGoMod := gosum.GoSumKey{ModulePath: "example.com/dep", Version: "v1.1.0", Path: "go.mod"}
+	kept := gosum.GoSumKey{ModulePath: "example.com/kept", Version: "v1.0.0"}
+
+	ancestor := gosum.GoSum{
+		old:  "h1:old=",
+		kept: "h1:kept=",
+	}
+
+	merged := gosum.GoSum{
+		old:         "h1:old=",
+		bumped:      "h1:bumped=",
+		bumpedGoMod: "h1:bumpedgomod=",
+		kept:        "h1:kept=",
+	}
+
+	// Pruned against a go.mod which doesn't hold the bump yet.
+	pruned := gosum.GoSum{
+		old:  "h1:old=",
+		kept: "h1:kept=",
+	}
+
+	restored, keys := gosum.KeepAdded(pruned, merged, ancestor)
+
+	assert.Equal(t, merged, restored)
+	assert.Equal(t, []gosum.GoSumKey{bumped, bumpedGoMod}, keys)
+}
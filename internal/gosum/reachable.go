@@ -0,0 +1,195 @@
+package gosum
+
+import (
+	"slices"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// goModPath is the path of the go.mod file hashes of a module version.
+const goModPath = "go.mod"
+
+// prunedGraphVersion is the first go version whose go.mod files list every
+// module providing a package to the build, as the module graph is pruned.
+const prunedGraphVersion = "v1.17"
+
+// PruneUnreachable returns the go.sum file without the hashes of module
+// versions the go.mod file can no longer reach, without needing network access.
+//
+// Only go.mod files at go 1.17 or later list the selected version of every
+// module providing a package, so the go.sum file of older go.mod files is
+// returned unchanged. Otherwise, module hashes are pruned for every version
+// other than the selected version of a required module, as no other version
+// provides packages. The go.mod hashes of versions lower than the selected
+// version are kept, as other modules in the module graph may still require
+// them, but those of higher versions are pruned, as they would have been
+// selected instead.
+func PruneUnreachable(sum GoSum, mod modfile.File) GoSum {
+	if !prunedGraph(mod) {
+		return sum
+	}
+
+	selected := selectedVersions(mod)
+	pruned := make(GoSum, len(sum))
+
+	for key, hash := range sum {
+		version, required := selected[key.ModulePath]
+
+		switch {
+		case required && key.Version == version:
+		case key.Path != goModPath:
+			// The module provides no packages, as it is not selected.
+			continue
+		case required && semver.Compare(key.Version, version) > 0:
+			continue
+		}
+
+		pruned[key] = hash
+	}
+
+	return pruned
+}
+
+// MissingGoModHashes returns the keys of the go.mod hashes missing from the
+// go.sum file for the module versions the go.mod file selects, in sorted order.
+// The go command can't load the module graph without them.
+func MissingGoModHashes(sum GoSum, mod modfile.File) []GoSumKey {
+	var missing []GoSumKey
+
+	for path, version := range selectedVersions(mod) {
+		key := GoSumKey{
+			ModulePath: path,
+			Version:    version,
+			Path:       goModPath,
+		}
+
+		if _, ok := sum[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	slices.SortFunc(missing, CompareKeys)
+
+	return missing
+}
+
+// MissingModuleHashes returns the keys of the module hashes missing from the
+// go.sum file for the module versions the go.mod file selects, in sorted order.
+//
+// Only modules providing packages need module hashes, which can't be known
+// without loading the packages, so only modules with a module hash for any
+// version in one of the earlier go.sum files, such as the sides of the merge,
+// are included.
+func MissingModuleHashes(sum GoSum, mod modfile.File, earlier ...GoSum) []GoSumKey {
+	providers := make(map[string]struct{})
+
+	for _, earlierSum := range earlier {
+		for key := range earlierSum {
+			if key.Path == "" {
+				providers[key.ModulePath] = struct{}{}
+			}
+		}
+	}
+
+	var missing []GoSumKey
+
+	for path, version := range selectedVersions(mod) {
+		if _, ok := providers[path]; !ok {
+			continue
+		}
+
+		key := GoSumKey{
+			ModulePath: path,
+			Version:    version,
+		}
+
+		if _, ok := sum[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	slices.SortFunc(missing, CompareKeys)
+
+	return missing
+}
+
+// MigratedModuleHashes returns the keys of the module hashes in the go.sum file
+// for modules the go.mod file no longer requires, as it requires the same
+// module at another major version path instead, in sorted order. They are left
+// behind when a merge collapses a module migrated to a major version path, such
+// as from github.com/x/y at v2.0.0+incompatible to github.com/x/y/v2, into its
+// migration, and the old module no longer provides packages unless another
+// module still requires it.
+func MigratedModuleHashes(sum GoSum, mod modfile.File) []GoSumKey {
+	required := make(map[string]struct{}, len(mod.Require))
+	migrated := make(map[string]struct{}, len(mod.Require))
+
+	for _, req := range mod.Require {
+		prefix, _ := gomod.MajorVersion(req.Mod)
+
+		required[req.Mod.Path] = struct{}{}
+		migrated[prefix] = struct{}{}
+	}
+
+	var keys []GoSumKey
+
+	for key := range sum {
+		if key.Path != "" {
+			continue
+		}
+
+		if _, ok := required[key.ModulePath]; ok {
+			continue
+		}
+
+		prefix, _ := gomod.MajorVersion(module.Version{Path: key.ModulePath, Version: key.Version})
+
+		if _, ok := migrated[prefix]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.SortFunc(keys, CompareKeys)
+
+	return keys
+}
+
+// prunedGraph reports whether the go.mod file has a pruned module graph.
+func prunedGraph(mod modfile.File) bool {
+	return mod.Go != nil && semver.Compare("v"+mod.Go.Version, prunedGraphVersion) >= 0
+}
+
+// selectedVersions maps the path of each module version the go.mod file
+// selects to its version, following replacements with other modules as the go
+// command does. Modules replaced with local directories have no hashes, so are
+// left out.
+func selectedVersions(mod modfile.File) map[string]string {
+	replacements := make(map[module.Version]module.Version)
+
+	for _, rep := range mod.Replace {
+		replacements[rep.Old] = rep.New
+	}
+
+	selected := make(map[string]string)
+
+	for _, req := range mod.Require {
+		// Replacements of a specific version take precedence over
+		// replacements of every version.
+		replacement, ok := replacements[req.Mod]
+		if !ok {
+			replacement, ok = replacements[module.Version{Path: req.Mod.Path}]
+		}
+
+		switch {
+		case !ok:
+			selected[req.Mod.Path] = req.Mod.Version
+		case replacement.Version != "":
+			selected[replacement.Path] = replacement.Version
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,169 @@
+package gosum_test
+
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/crystalix007/go-merge-drivers/internal/gosum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneUnreachable(t *testing.T) {
+	t.Parallel()
+
+	mod, err := gomod.ParseData("go.mod", []byte(`module example.com/m
+
+go 1.22
+
+require (
+	example.com/dep v1.2.0
+	example.com/forked v1.0.0
+	example.com/local v1.0.0
+)
+
+replace example.com/forked => example.com/fork v1.0.1
+
+replace example.com/local => ../local
+`))
+	require.NoError(t, err)
+
+	sum := gosum.GoSum{
+		// The selected version of a required module is kept.
+		{ModulePath: "example.com/dep", Version: "v1.2.0"}:                 "h1:dep120=",
+		{ModulePath: "example.com/dep", Version: "v1.2.0", Path: "go.mod"}: "h1:dep120mod=",
+
+		// Lower versions may still be in the module graph, but provide no
+		// packages.
+		{ModulePath: "example.com/dep", Version: "v1.1.0"}:                 "h1:dep110=",
+		{ModulePath: "example.com/dep", Version: "v1.1.0", Path: "go.mod"}: "h1:dep110mod=",
+
+		// Higher versions would have been selected instead.
+		{ModulePath: "example.com/dep", Version: "v1.3.0"}:                 "h1:dep130=",
+		{ModulePath: "example.com/dep", Version: "v1.3.0", Path: "go.mod"}: "h1:dep130mod=",
+
+		// Replacements are selected in place of the modules they replace.
+		{ModulePath: "example.com/fork", Version: "v1.0.1"}:                 "h1:fork=",
+		{ModulePath: "example.com/fork", Version: "v1.0.1", Path: "go.mod"}: "h1:forkmod=",
+
+		// Modules no longer required provide no packages.
+		{ModulePath: "example.com/removed", Version: "v1.0.0"}:                 "h1:removed=",
+		{ModulePath: "example.com/removed", Version: "v1.0.0", Path: "go.mod"}: "h1:removedmod=",
+	}
+
+	assert.Equal(t, gosum.GoSum{
+		{ModulePath: "example.com/dep", Version: "v1.2.0"}:                     "h1:dep120=",
+		{ModulePath: "example.com/dep", Version: "v1.2.0", Path: "go.mod"}:     "h1:dep120mod=",
+		{ModulePath: "example.com/dep", Version: "v1.1.0", Path: "go.mod"}:     "h1:dep110mod=",
+		{ModulePath: "example.com/fork", Version: "v1.0.1"}:                    "h1:fork=",
+		{ModulePath: "example.com/fork", Version: "v1.0.1", Path: "go.mod"}:    "h1:forkmod=",
+		{ModulePath: "example.com/removed", Version: "v1.0.0", Path: "go.mod"}: "h1:removedmod=",
+	}, gosum.PruneUnreachable(sum, *mod))
+}
+
+func TestPruneUnreachable_unprunedGraph(t *testing.T) {
+	t.Parallel()
+
+	mod, err := gomod.ParseData("go.mod", []byte(`module example.com/m
+
+go 1.16
+
+require example.com/dep v1.2.0
+`))
+	require.NoError(t, err)
+
+	// A dependency may require a higher version than the go.mod file.
+	sum := gosum.GoSum{
+		{ModulePath: "example.com/dep", Version: "v1.3.0"}: "h1:dep130=",
+	}
+
+	assert.Equal(t, sum, gosum.PruneUnreachable(sum, *mod))
+}
+
+func TestMissingGoModHashes(t *testing.T) {
+	t.Parallel()
+
+	mod, err := gomod.ParseData("go.mod", []byte(`module example.com/m
+
+go 1.22
+
+require (
+	example.com/dep v1.2.0
+	example.com/missing v1.0.0
+	example.com/local v1.0.0
+)
+
+replace example.com/local => ../local
+`))
+	require.NoError(t, err)
+
+	sum := gosum.GoSum{
+		{ModulePath: "example.com/dep", Version: "v1.2.0", Path: "go.mod"}: "h1:dep120mod=",
+		{ModulePath: "example.com/missing", Version: "v1.0.0"}:             "h1:missing=",
+	}
+
+	assert.Equal(t, []gosum.GoSumKey{
+		{ModulePath: "example.com/missing", Version: "v1.0.0", Path: "go.mod"},
+	}, gosum.MissingGoModHashes(sum, *mod))
+}
+
+func TestMissingModuleHashes(t *testing.T) {
+	t.Parallel()
+
+	mod, err := gomod.ParseData("go.mod", []byte(`module example.com/m
+
+go 1.22
+
+require (
+	example.com/bumped v1.1.0
+	example.com/kept v1.0.0
+	example.com/nopackages v1.0.0
+)
+`))
+	require.NoError(t, err)
+
+	sum := gosum.GoSum{
+		{ModulePath: "example.com/kept", Version: "v1.0.0"}: "h1:kept=",
+	}
+
+	// Before the merge, each module providing packages had a module hash.
+	earlier := gosum.GoSum{
+		{ModulePath: "example.com/bumped", Version: "v1.0.0"}:                     "h1:bumped=",
+		{ModulePath: "example.com/kept", Version: "v1.0.0"}:                       "h1:kept=",
+		{ModulePath: "example.com/nopackages", Version: "v1.0.0", Path: "go.mod"}: "h1:nopackages=",
+	}
+
+	assert.Equal(t, []gosum.GoSumKey{
+		{ModulePath: "example.com/bumped", Version: "v1.1.0"},
+	}, gosum.MissingModuleHashes(sum, *mod, earlier))
+}
+
+func TestMigratedModuleHashes(t *testing.T) {
+	t.Parallel()
+
+	mod, err := gomod.ParseData("go.mod", []byte(`module example.com/m
+
+go 1.22
+
+require (
+	github.com/x/y/v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
+	example.com/kept v1.0.0
+)
+`))
+	require.NoError(t, err)
+
+	sum := gosum.GoSum{
+		{ModulePath: "github.com/x/y", Version: "v2.0.1+incompatible"}:                 "h1:y=",
+		{ModulePath: "github.com/x/y", Version: "v2.0.1+incompatible", Path: "go.mod"}: "h1:ygomod=",
+		{ModulePath: "github.com/x/y/v2", Version: "v2.1.0"}:                           "h1:yv2=",
+		{ModulePath: "gopkg.in/yaml.v2", Version: "v2.4.0"}:                            "h1:yaml=",
+		{ModulePath: "example.com/kept", Version: "v1.0.0"}:                            "h1:kept=",
+		{ModulePath: "example.com/other", Version: "v1.0.0"}:                           "h1:other=",
+	}
+
+	assert.Equal(t, []gosum.GoSumKey{
+		{ModulePath: "github.com/x/y", Version: "v2.0.1+incompatible"},
+		{ModulePath: "gopkg.in/yaml.v2", Version: "v2.4.0"},
+	}, gosum.MigratedModuleHashes(sum, *mod))
+}
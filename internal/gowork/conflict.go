@@ -0,0 +1,21 @@
+package gowork
+
+import (
+	"fmt"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+	"golang.org/x/mod/modfile"
+)
+
+// FormatConflicts formats the merged go.work file, surrounding the statements
+// of each conflict with git-style conflict markers, in the same way as
+// [gomod.FormatConflicts].
+func FormatConflicts(merged modfile.WorkFile, conflicts []gomod.Conflict, labels markers.Labels) ([]byte, error) {
+	formatted, err := gomod.FormatConflicts(modFile(merged), conflicts, labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format go.work file: %w", err)
+	}
+
+	return formatted, nil
+}
@@ -0,0 +1,55 @@
+package gowork_test
+
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gowork"
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatConflicts_replace(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseWorkFile(t, `go 1.22.0
+
+use ./a
+`)
+
+	current := parseWorkFile(t, `go 1.22.0
+
+use ./a
+
+replace example.com/dep => ../dep
+`)
+
+	other := parseWorkFile(t, `go 1.22.0
+
+use ./a
+
+replace example.com/dep => ../other-dep
+`)
+
+	merged, conflicts, err := gowork.Merge(*current, *other, *ancestor)
+
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+
+	formatted, err := gowork.FormatConflicts(merged, conflicts, markers.Labels{
+		Current: "HEAD",
+		Other:   "feature",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `go 1.22.0
+
+use ./a
+
+<<<<<<< HEAD
+replace example.com/dep => ../dep
+=======
+replace example.com/dep => ../other-dep
+>>>>>>> feature
+`, string(formatted))
+}
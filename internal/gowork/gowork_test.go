@@ -0,0 +1,18 @@
+package gowork_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+// parseWorkFile parses the given go.work file contents.
+func parseWorkFile(t *testing.T, contents string) *modfile.WorkFile {
+	t.Helper()
+
+	work, err := modfile.ParseWork("go.work", []byte(contents), nil)
+	require.NoError(t, err)
+
+	return work
+}
@@ -0,0 +1,88 @@
+package gowork
+
+import (
+	"github.com/crystalix007/go-merge-drivers/internal/gomod"
+	"golang.org/x/mod/modfile"
+)
+
+// Merge merges the changes between the common ancestor and other go.work files
+// into the current go.work file.
+//
+// The go, toolchain, godebug and replace statements are shared with go.mod
+// files, so are merged by the same rules as [gomod.Merge]. The use statements
+// are merged as a set of directories: directories added on the other side are
+// added, and directories removed on the other side are removed. As use
+// statements have nothing but their directory, they never conflict.
+//
+// The current file is edited in place, so its comments, blocks and ordering are
+// kept for every statement the other side didn't change.
+func Merge(current, other, ancestor modfile.WorkFile) (modfile.WorkFile, []gomod.Conflict, error) {
+	return MergeWithOptions(current, other, ancestor, gomod.Options{})
+}
+
+// MergeWithOptions merges the go.work files like [Merge], with the statements
+// shared with go.mod files merged as [gomod.MergeWithOptions] would. It returns
+// an error if the current file has no syntax to edit, such as one built by hand
+// rather than parsed, or the merged file can't be parsed again.
+func MergeWithOptions(current, other, ancestor modfile.WorkFile, opts gomod.Options) (modfile.WorkFile, []gomod.Conflict, error) {
+	merged, err := clone(current)
+	if err != nil {
+		return modfile.WorkFile{}, nil, err
+	}
+
+	mergedMod := modFile(*merged)
+
+	conflicts := gomod.MergeInto(&mergedMod, modFile(current), modFile(other), modFile(ancestor), opts)
+
+	mergeUses(merged, other, ancestor)
+
+	merged.Cleanup()
+
+	// Statements edited through the go.mod file aren't updated in the go.work
+	// file, so parse the merged file again.
+	reparsed, err := clone(*merged)
+	if err != nil {
+		return modfile.WorkFile{}, nil, err
+	}
+
+	return *reparsed, conflicts, nil
+}
+
+// mergeUses merges the use statements added and removed by the other side into
+// the merged file.
+func mergeUses(merged *modfile.WorkFile, other, ancestor modfile.WorkFile) {
+	ancestorUses := make(map[string]struct{})
+
+	for _, use := range ancestor.Use {
+		ancestorUses[use.Path] = struct{}{}
+	}
+
+	otherUses := make(map[string]struct{})
+
+	for _, use := range other.Use {
+		otherUses[use.Path] = struct{}{}
+
+		if _, ok := ancestorUses[use.Path]; !ok {
+			merged.AddUse(use.Path, use.ModulePath)
+		}
+	}
+
+	for _, use := range ancestor.Use {
+		if _, ok := otherUses[use.Path]; !ok {
+			merged.DropUse(use.Path)
+		}
+	}
+}
+
+// modFile returns a go.mod file holding the statements the go.work file shares
+// with go.mod files. The go.mod file shares the syntax of the go.work file, so
+// edits to it are made to the go.work file too.
+func modFile(work modfile.WorkFile) modfile.File {
+	return modfile.File{
+		Go:        work.Go,
+		Toolchain: work.Toolchain,
+		Godebug:   work.Godebug,
+		Replace:   work.Replace,
+		Syntax:    work.Syntax,
+	}
+}
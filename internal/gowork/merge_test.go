@@ -0,0 +1,142 @@
+package gowork_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gowork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	current, err := gowork.Parse("testdata/current.go.work")
+	require.NoError(t, err)
+
+	other, err := gowork.Parse("testdata/other.go.work")
+	require.NoError(t, err)
+
+	ancestor, err := gowork.Parse("testdata/ancestor.go.work")
+	require.NoError(t, err)
+
+	merged, conflicts, err := gowork.Merge(*current, *other, *ancestor)
+
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	// Use the current go version, as it is greater.
+	assert.Equal(t, "1.23.0", merged.Go.Version)
+
+	// Use the toolchain added by the other side.
+	require.NotNil(t, merged.Toolchain)
+	assert.Equal(t, "go1.24.1", merged.Toolchain.Name)
+
+	// Each side's added directories are kept, and the removed directory is
+	// dropped.
+	assert.Equal(t, []string{"./api", "./cmd", "./tools", "./web"}, usePaths(merged))
+
+	// Use the other replacement, as it has the greater version.
+	require.Len(t, merged.Replace, 1)
+	assert.Equal(t, "v1.2.0", merged.Replace[0].New.Version)
+
+	actual := modfile.Format(merged.Syntax)
+
+	expected, err := os.ReadFile("testdata/expected.go.work")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestMerge_godebugConflict(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseWorkFile(t, `go 1.22.0
+
+use ./a
+
+godebug panicnil=1
+`)
+
+	current := parseWorkFile(t, `go 1.22.0
+
+use ./a
+
+godebug panicnil=0
+`)
+
+	other := parseWorkFile(t, `go 1.22.0
+
+use (
+	./a
+	./b
+)
+`)
+
+	merged, conflicts, err := gowork.Merge(*current, *other, *ancestor)
+
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "godebug panicnil: removed on one side but changed on the other", conflicts[0].String())
+
+	// The current setting is kept, and the other directory is still added.
+	require.Len(t, merged.Godebug, 1)
+	assert.Equal(t, "0", merged.Godebug[0].Value)
+	assert.Equal(t, []string{"./a", "./b"}, usePaths(merged))
+}
+
+func TestMerge_noSyntax(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseWorkFile(t, "go 1.22.0\n")
+	other := parseWorkFile(t, "go 1.22.0\n\nuse ./a\n")
+
+	_, _, err := gowork.Merge(modfile.WorkFile{}, *other, *ancestor)
+	require.ErrorIs(t, err, gowork.ErrNoSyntax)
+}
+
+func TestMerge_removedUse(t *testing.T) {
+	t.Parallel()
+
+	ancestor := parseWorkFile(t, `go 1.22.0
+
+use (
+	./a
+	./b
+)
+`)
+
+	current := parseWorkFile(t, `go 1.22.0
+
+use ./a
+`)
+
+	other := parseWorkFile(t, `go 1.22.0
+
+use (
+	./a
+	./b
+	./c
+)
+`)
+
+	merged, conflicts, err := gowork.Merge(*current, *other, *ancestor)
+
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	assert.Equal(t, []string{"./a", "./c"}, usePaths(merged))
+}
+
+// usePaths returns the directories of the use statements of the go.work file.
+func usePaths(work modfile.WorkFile) []string {
+	paths := make([]string, 0, len(work.Use))
+
+	for _, use := range work.Use {
+		paths = append(paths, use.Path)
+	}
+
+	return paths
+}
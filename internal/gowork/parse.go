@@ -0,0 +1,67 @@
+package gowork
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ErrNoSyntax is returned when merging a go.work file which wasn't parsed, and
+// so has no syntax to edit.
+var ErrNoSyntax = errors.New("gowork: go.work file has no syntax")
+
+// Parse parses the contents of a go.work file and returns a modfile.WorkFile
+// object.
+func Parse(filename string) (*modfile.WorkFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to read go.work file (%s): %w",
+			filename,
+			err,
+		)
+	}
+
+	return ParseData(filename, data)
+}
+
+// ParseData parses the contents of a go.work file already read, such as from a
+// git object, and returns a modfile.WorkFile object.
+func ParseData(filename string, data []byte) (*modfile.WorkFile, error) {
+	work, err := modfile.ParseWork(filename, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to parse go.work file (%s): %w",
+			filename,
+			err,
+		)
+	}
+
+	work.Cleanup()
+
+	return work, nil
+}
+
+// clone returns a deep copy of the go.work file, by formatting and parsing it
+// again. This also brings the parsed statements up to date with any edits made
+// to the syntax.
+func clone(f modfile.WorkFile) (*modfile.WorkFile, error) {
+	if f.Syntax == nil {
+		return nil, ErrNoSyntax
+	}
+
+	data := modfile.Format(f.Syntax)
+
+	cloned, err := modfile.ParseWork(f.Syntax.Name, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to parse formatted go.work file (%s): %w",
+			f.Syntax.Name,
+			err,
+		)
+	}
+
+	return cloned, nil
+}
@@ -0,0 +1,26 @@
+package gowork
+
+import (
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ModuleDirs returns the directories of the modules used by the go.work file,
+// resolving relative directories against dir, the directory holding the
+// go.work file.
+func ModuleDirs(work modfile.WorkFile, dir string) []string {
+	dirs := make([]string, 0, len(work.Use))
+
+	for _, use := range work.Use {
+		path := filepath.FromSlash(use.Path)
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		dirs = append(dirs, path)
+	}
+
+	return dirs
+}
@@ -0,0 +1,30 @@
+package gowork_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gowork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleDirs(t *testing.T) {
+	t.Parallel()
+
+	work := parseWorkFile(t, `go 1.22.0
+
+use (
+	.
+	./api
+	../shared
+)
+`)
+
+	dir := filepath.FromSlash("/src/workspace")
+
+	assert.Equal(t, []string{
+		filepath.FromSlash("/src/workspace"),
+		filepath.FromSlash("/src/workspace/api"),
+		filepath.FromSlash("/src/shared"),
+	}, gowork.ModuleDirs(*work, dir))
+}
@@ -0,0 +1,45 @@
+package markers
+
+import "strings"
+
+// Size is the length of the conflict markers, matching git's default.
+const Size = 7
+
+// Labels are the names given to each side of a conflict in the conflict
+// markers.
+type Labels struct {
+	Current string
+	Other   string
+}
+
+// DefaultLabels are the labels used when git does not provide any.
+var DefaultLabels = Labels{
+	Current: "current",
+	Other:   "other",
+}
+
+// Block returns the lines of a git-style conflict block, holding the current
+// lines and then the other lines.
+func Block(current, other []string, labels Labels) []string {
+	block := make([]string, 0, len(current)+len(other)+3)
+
+	block = append(block, marker("<", labels.Current))
+	block = append(block, current...)
+	block = append(block, strings.Repeat("=", Size))
+	block = append(block, other...)
+	block = append(block, marker(">", labels.Other))
+
+	return block
+}
+
+// marker returns a conflict marker line, made of the marker character and
+// followed by the label if any.
+func marker(char string, label string) string {
+	marker := strings.Repeat(char, Size)
+
+	if label == "" {
+		return marker
+	}
+
+	return marker + " " + label
+}
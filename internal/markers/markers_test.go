@@ -0,0 +1,39 @@
+package markers_test
+
+import (
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/markers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlock(t *testing.T) {
+	t.Parallel()
+
+	block := markers.Block(
+		[]string{"current"},
+		[]string{"other"},
+		markers.Labels{Current: "HEAD", Other: "feature"},
+	)
+
+	assert.Equal(t, []string{
+		"<<<<<<< HEAD",
+		"current",
+		"=======",
+		"other",
+		">>>>>>> feature",
+	}, block)
+}
+
+func TestBlock_noLabels(t *testing.T) {
+	t.Parallel()
+
+	block := markers.Block(nil, []string{"other"}, markers.Labels{})
+
+	assert.Equal(t, []string{
+		"<<<<<<<",
+		"=======",
+		"other",
+		">>>>>>>",
+	}, block)
+}
@@ -0,0 +1,304 @@
+// Package sumdb verifies go.sum hashes against a checksum database, such as
+// sum.golang.org, configured in the same way as the GOSUMDB environment
+// variable of the go command.
+package sumdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gosum"
+	"golang.org/x/mod/module"
+	modsumdb "golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// DefaultGOSUMDB is the checksum database used when GOSUMDB is not set.
+const DefaultGOSUMDB = "sum.golang.org"
+
+var (
+	// ErrDisabled is returned when the checksum database is disabled with
+	// GOSUMDB=off.
+	ErrDisabled = errors.New("sumdb: checksum database disabled by GOSUMDB=off")
+
+	// ErrInvalidGOSUMDB is returned when GOSUMDB is not a valid checksum
+	// database configuration.
+	ErrInvalidGOSUMDB = errors.New("sumdb: invalid GOSUMDB")
+)
+
+// knownKeys holds the verifier keys of well-known checksum databases, so
+// GOSUMDB may name them without their key, as with the go command.
+var knownKeys = map[string]string{
+	"sum.golang.org": "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ux18htTTAD8OuAn8",
+}
+
+// Verifier verifies go.sum hashes against a checksum database.
+type Verifier struct {
+	// Name is the name of the checksum database.
+	Name string
+
+	client *modsumdb.Client
+	ops    *clientOps
+}
+
+// NewVerifier returns a verifier for the checksum database configured by
+// gosumdb, which takes the same forms as GOSUMDB: the name of a well-known
+// checksum database, or a verifier key, optionally followed by the URL of the
+// database. Modules matching the nosumdb patterns, which take the same form as
+// GONOSUMDB, are not verified.
+func NewVerifier(ctx context.Context, gosumdb, nosumdb string) (*Verifier, error) {
+	// sum.golang.google.cn is an alias for sum.golang.org, as in the go
+	// command.
+	if gosumdb == "sum.golang.google.cn" {
+		gosumdb = "sum.golang.org https://sum.golang.google.cn"
+	}
+
+	if gosumdb == "off" {
+		return nil, ErrDisabled
+	}
+
+	fields := strings.Fields(gosumdb)
+
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidGOSUMDB, gosumdb)
+	}
+
+	key := fields[0]
+
+	if known, ok := knownKeys[key]; ok {
+		key = known
+	}
+
+	verifier, err := note.NewVerifier(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGOSUMDB, err)
+	}
+
+	base := "https://" + verifier.Name()
+
+	if len(fields) == 2 {
+		base = fields[1]
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid URL: %w", ErrInvalidGOSUMDB, err)
+	}
+
+	ops := &clientOps{
+		ctx:      ctx,
+		key:      key,
+		baseURL:  baseURL,
+		config:   make(map[string][]byte),
+		notFound: make(map[string]bool),
+	}
+
+	client := modsumdb.NewClient(ops)
+	client.SetGONOSUMDB(nosumdb)
+
+	return &Verifier{
+		Name:   verifier.Name(),
+		client: client,
+		ops:    ops,
+	}, nil
+}
+
+// Verify looks up the hash of each key in the checksum database, and returns
+// a conflict for each hash which doesn't match the database's, with the hash
+// the database records. Keys the database doesn't know, or which aren't
+// verified because they match the nosumdb patterns, are returned as unverified.
+//
+// An error is returned if any other lookup fails, such as when the checksum
+// database can't be reached, so verification never silently passes, or if the
+// checksum database itself is misbehaving, such as by serving an inconsistent
+// log.
+func (v *Verifier) Verify(sum gosum.GoSum, keys []gosum.GoSumKey) ([]gosum.Conflict, []gosum.GoSumKey, error) {
+	var (
+		conflicts  []gosum.Conflict
+		unverified []gosum.GoSumKey
+	)
+
+	for _, key := range keys {
+		version := key.Version
+
+		if key.Path != "" {
+			version += "/" + key.Path
+		}
+
+		lines, err := v.client.Lookup(key.ModulePath, version)
+
+		switch {
+		case errors.Is(err, modsumdb.ErrGONOSUMDB),
+			err != nil && v.ops.lookupNotFound(key.ModulePath, key.Version),
+			err == nil && len(lines) == 0:
+			unverified = append(unverified, key)
+
+			continue
+		case errors.Is(err, modsumdb.ErrSecurity), err != nil && len(v.ops.securityMessages()) > 0:
+			// The client doesn't wrap the errors it returns from lookups,
+			// so security errors are told apart by those it recorded.
+			return nil, nil, fmt.Errorf(
+				"failed to verify go.sum hashes with checksum database %s: %w: %s",
+				v.Name,
+				modsumdb.ErrSecurity,
+				strings.Join(v.ops.securityMessages(), "; "),
+			)
+		case err != nil:
+			return nil, nil, fmt.Errorf(
+				"failed to look up %s in checksum database %s: %w",
+				key,
+				v.Name,
+				err,
+			)
+		}
+
+		fields := strings.Fields(lines[0])
+		expected := gosum.GoSumHash(fields[len(fields)-1])
+
+		if sum[key] != expected {
+			conflicts = append(conflicts, gosum.Conflict{
+				Key:      key,
+				Current:  sum[key],
+				Database: v.Name,
+				Expected: expected,
+			})
+		}
+	}
+
+	return conflicts, unverified, nil
+}
+
+// clientOps implements the operations the checksum database client needs,
+// fetching from the database over HTTP and keeping its configuration in
+// memory, so nothing is written to disk.
+type clientOps struct {
+	ctx     context.Context
+	key     string
+	baseURL *url.URL
+
+	mu     sync.Mutex
+	config map[string][]byte
+
+	// notFound holds the lookup paths the database doesn't know.
+	notFound map[string]bool
+
+	securityErrors []string
+}
+
+// Ensure [clientOps] implements the [modsumdb.ClientOps] interface.
+var _ modsumdb.ClientOps = (*clientOps)(nil)
+
+// ReadRemote reads the path from the checksum database.
+func (o *clientOps) ReadRemote(path string) ([]byte, error) {
+	target := o.baseURL.JoinPath(path)
+
+	req, err := http.NewRequestWithContext(o.ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checksum database request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from checksum database: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		o.mu.Lock()
+		o.notFound[path] = true
+		o.mu.Unlock()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to read from checksum database: %s: %s", target, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from checksum database: %w", err)
+	}
+
+	return data, nil
+}
+
+// ReadConfig reads the verifier key, or the latest known signed tree, which
+// starts empty.
+func (o *clientOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(o.key), nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.config[file], nil
+}
+
+// WriteConfig updates the latest known signed tree.
+func (o *clientOps) WriteConfig(file string, old, new []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if string(o.config[file]) != string(old) {
+		return modsumdb.ErrWriteConflict
+	}
+
+	o.config[file] = new
+
+	return nil
+}
+
+// ReadCache reports every cache file as missing, as nothing is cached.
+func (o *clientOps) ReadCache(file string) ([]byte, error) {
+	return nil, fmt.Errorf("no cache file: %s", file)
+}
+
+// WriteCache discards the cache file, as nothing is cached.
+func (o *clientOps) WriteCache(string, []byte) {}
+
+// Log discards the log message.
+func (o *clientOps) Log(string) {}
+
+// SecurityError records the security error. The client then returns
+// [modsumdb.ErrSecurity] from the failing lookup.
+func (o *clientOps) SecurityError(msg string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.securityErrors = append(o.securityErrors, msg)
+}
+
+// lookupNotFound reports whether the database doesn't know the module version,
+// as the go.mod hash of a version is looked up along with its module hash.
+func (o *clientOps) lookupNotFound(modulePath, version string) bool {
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return false
+	}
+
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.notFound["/lookup/"+escapedPath+"@"+escapedVersion]
+}
+
+// securityMessages returns the security errors recorded so far.
+func (o *clientOps) securityMessages() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return slices.Clone(o.securityErrors)
+}
@@ -0,0 +1,142 @@
+package sumdb_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/crystalix007/go-merge-drivers/internal/gosum"
+	"github.com/crystalix007/go-merge-drivers/internal/sumdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	modsumdb "golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+)
+
+var (
+	depKey    = gosum.GoSumKey{ModulePath: "example.com/dep", Version: "v1.0.0"}
+	depModKey = gosum.GoSumKey{ModulePath: "example.com/dep", Version: "v1.0.0", Path: "go.mod"}
+	brokenKey = gosum.GoSumKey{ModulePath: "example.com/broken", Version: "v1.0.0"}
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	gosumdb := startSumDB(t)
+
+	verifier, err := sumdb.NewVerifier(context.Background(), gosumdb, "")
+	require.NoError(t, err)
+	assert.Equal(t, "sumdb.example.com", verifier.Name)
+
+	unknownKey := gosum.GoSumKey{ModulePath: "example.com/unknown", Version: "v1.0.0"}
+
+	sum := gosum.GoSum{
+		depKey:     "h1:tampered=",
+		depModKey:  "h1:depmod=",
+		unknownKey: "h1:unknown=",
+	}
+
+	conflicts, unverified, err := verifier.Verify(sum, []gosum.GoSumKey{depKey, depModKey, unknownKey})
+	require.NoError(t, err)
+
+	assert.Equal(t, []gosum.Conflict{{
+		Key:      depKey,
+		Current:  "h1:tampered=",
+		Database: "sumdb.example.com",
+		Expected: "h1:dep=",
+	}}, conflicts)
+	assert.Equal(t, []gosum.GoSumKey{unknownKey}, unverified)
+}
+
+func TestVerifier_Verify_nosumdb(t *testing.T) {
+	t.Parallel()
+
+	gosumdb := startSumDB(t)
+
+	verifier, err := sumdb.NewVerifier(context.Background(), gosumdb, "example.com/dep")
+	require.NoError(t, err)
+
+	sum := gosum.GoSum{depKey: "h1:tampered="}
+
+	conflicts, unverified, err := verifier.Verify(sum, []gosum.GoSumKey{depKey})
+	require.NoError(t, err)
+
+	assert.Empty(t, conflicts)
+	assert.Equal(t, []gosum.GoSumKey{depKey}, unverified)
+}
+
+func TestVerifier_Verify_lookupFailure(t *testing.T) {
+	t.Parallel()
+
+	gosumdb := startSumDB(t)
+
+	verifier, err := sumdb.NewVerifier(context.Background(), gosumdb, "")
+	require.NoError(t, err)
+
+	sum := gosum.GoSum{brokenKey: "h1:broken="}
+
+	_, _, err = verifier.Verify(sum, []gosum.GoSumKey{brokenKey})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, modsumdb.ErrSecurity)
+}
+
+func TestVerifier_Verify_unreachable(t *testing.T) {
+	t.Parallel()
+
+	_, verifierKey, err := note.GenerateKey(nil, "sumdb.example.com")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	verifier, err := sumdb.NewVerifier(context.Background(), verifierKey+" "+server.URL, "")
+	require.NoError(t, err)
+
+	sum := gosum.GoSum{depKey: "h1:dep="}
+
+	_, _, err = verifier.Verify(sum, []gosum.GoSumKey{depKey})
+	require.Error(t, err)
+}
+
+func TestNewVerifier_invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := sumdb.NewVerifier(context.Background(), "off", "")
+	require.ErrorIs(t, err, sumdb.ErrDisabled)
+
+	_, err = sumdb.NewVerifier(context.Background(), "sumdb.example.com", "")
+	require.ErrorIs(t, err, sumdb.ErrInvalidGOSUMDB)
+
+	_, err = sumdb.NewVerifier(context.Background(), "sum.golang.org https://example.com extra", "")
+	require.ErrorIs(t, err, sumdb.ErrInvalidGOSUMDB)
+}
+
+// startSumDB starts a checksum database serving the hashes of example.com/dep
+// v1.0.0, and failing to look up example.com/broken, returning its GOSUMDB
+// configuration.
+func startSumDB(t *testing.T) string {
+	t.Helper()
+
+	signer, verifier, err := note.GenerateKey(nil, "sumdb.example.com")
+	require.NoError(t, err)
+
+	testServer := modsumdb.NewTestServer(signer, func(path, version string) ([]byte, error) {
+		switch {
+		case path == brokenKey.ModulePath:
+			return nil, fmt.Errorf("failed to look up %s@%s", path, version)
+		case path != depKey.ModulePath || version != depKey.Version:
+			// The server reports modules it doesn't know as not found.
+			return nil, os.ErrNotExist
+		}
+
+		return []byte("example.com/dep v1.0.0 h1:dep=\nexample.com/dep v1.0.0/go.mod h1:depmod=\n"), nil
+	})
+
+	server := httptest.NewServer(modsumdb.NewServer(testServer))
+	t.Cleanup(server.Close)
+
+	return verifier + " " + server.URL
+}